@@ -1,12 +1,14 @@
 package main
 
 import (
+	"backend/internal/controllers"
 	"backend/internal/initializers"
 	"backend/internal/middleware"
 	"backend/internal/models"
 	"backend/internal/routes"
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,23 +16,44 @@ import (
 func init() {
 	//initializers.LoadEnvVariables()
 	initializers.ConnectDatabase()
+	initializers.SetupStorage()
 }
 
 func RunMigrations() {
+	if err := models.MigrateFavorites(initializers.DB); err != nil {
+		log.Fatalf("Failed to migrate favorites: %v", err)
+	}
+
 	err := initializers.DB.AutoMigrate(
 		&models.User{},
 		&models.UserEmailVerification{},
 		&models.UserPasswordReset{},
 		&models.Audio{},
 		&models.Playlist{},
+		&models.PlaylistAudio{},
+		&models.PlaylistCollaborator{},
+		&models.PlaylistActivity{},
 		&models.Token{},
 		&models.Favorite{},
 		&models.User_Relations{},
+		&models.History{},
+		&models.UploadSession{},
+		&models.Waveform{},
+		&models.ShareLink{},
+		&models.Activity{},
 	)
 
 	if err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
+
+	if err := models.MigrateShareLinks(initializers.DB); err != nil {
+		log.Fatalf("Failed to migrate share links: %v", err)
+	}
+
+	if err := models.CreateSearchIndexes(initializers.DB); err != nil {
+		log.Fatalf("Failed to create search indexes: %v", err)
+	}
 }
 
 func main() {
@@ -47,6 +70,12 @@ func main() {
 		RunMigrations()
 	}
 
+	go func() {
+		for range time.Tick(time.Hour) {
+			controllers.CleanupExpiredUploads()
+		}
+	}()
+
 	userRoutes := router.Group("/users")
 	{
 		routes.SetUserRoutes(userRoutes)
@@ -74,6 +103,22 @@ func main() {
 	{
 		routes.SetProfileRoutes(profileRoutes)
 	}
+	searchRoutes := router.Group("/search")
+	{
+		routes.SetSearchRoutes(searchRoutes)
+	}
+	shareRoutes := router.Group("/share")
+	{
+		routes.SetShareRoutes(shareRoutes)
+	}
+	feedRoutes := router.Group("/feed")
+	{
+		routes.SetFeedRoutes(feedRoutes)
+	}
+	fileRoutes := router.Group("/files")
+	{
+		routes.SetFileRoutes(fileRoutes)
+	}
 
 	router.Run()
 }
@@ -3,21 +3,51 @@ package controllers
 import (
 	"backend/internal/initializers"
 	"backend/internal/models"
+	"backend/internal/pagination"
+	"backend/internal/utils"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
 /*
-* This method fetches and returns all registered users.
+* This method fetches and returns registered users, newest first.
+* Supports 'limit' and 'cursor' query parameters.
  */
 func GetAllUsers(c *gin.Context) {
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+
 	var users []models.User
-	if result := initializers.DB.Find(&users); result.Error != nil {
+	if result := initializers.DB.Scopes(page.Scope("created_at", "id")).Find(&users); result.Error != nil {
 		c.Error(result.Error)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"users": users})
+
+	users, next := pagination.Trim(page, users, userCursor)
+
+	userList := make([]gin.H, len(users))
+	for i, user := range users {
+		userList[i] = gin.H{
+			"id":         user.ID,
+			"name":       user.Name,
+			"email":      user.Email,
+			"avatar":     utils.FileURL(user.AvatarURL),
+			"verified":   user.Verified,
+			"is_admin":   user.IsAdmin,
+			"banned":     user.Banned,
+			"created_at": user.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, pagination.Envelope(userList, next))
+}
+
+func userCursor(user models.User) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: user.CreatedAt, ID: user.ID}
 }
 
 func DeleteAudioById(c *gin.Context) {
@@ -2,11 +2,22 @@ package controllers
 
 import (
 	"backend/internal/initializers"
+	"backend/internal/media"
 	"backend/internal/models"
+	"backend/internal/pagination"
+	"backend/internal/serializers"
+	"backend/internal/storage"
 	"backend/internal/utils"
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 /*
@@ -25,69 +36,119 @@ func CreateAudio(c *gin.Context) {
 		return
 	}
 
+	audioFile, err := c.FormFile("audioFile")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Audio file is missing"})
+		return
+	}
+
+	createAudio(c, userModel, audioSource{
+		Filename:    audioFile.Filename,
+		Size:        audioFile.Size,
+		ContentType: audioFile.Header.Get("Content-Type"),
+		Open: func() (io.ReadSeekCloser, error) {
+			return audioFile.Open()
+		},
+	})
+}
+
+// audioSource is an uploaded audio file, either a multipart field or an assembled resumable upload.
+type audioSource struct {
+	Filename    string
+	Size        int64
+	ContentType string
+	Open        func() (io.ReadSeekCloser, error)
+}
+
+/*
+* createAudio stores the audio file and its cover, reads the embedded metadata and saves the audio record.
+* Title, about and category come from the form; explicit form fields override extracted tags.
+* An optional 'visibility' field (public or private) keeps unreleased uploads to their owner and share links.
+ */
+func createAudio(c *gin.Context, userModel *models.User, source audioSource) {
 	title := c.PostForm("title")
 	about := c.PostForm("about")
 	category := c.PostForm("category")
+	visibility := c.DefaultPostForm("visibility", models.AudioPublic)
+
+	var audioURL, coverURL, audioPublicID, coverPublicID string
+
+	meta := readAudioMetadata(source)
+	if title == "" {
+		title = meta.Title
+	}
 
 	if title == "" || about == "" || category == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required fields"})
 		return
 	}
 
-	var audioURL, coverURL, audioPublicID, coverPublicID string
-	audioFile, err := c.FormFile("audioFile")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Audio file is missing"})
+	if !isAudioVisibility(visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be public or private"})
 		return
 	}
 
-	audioFilePath := audioFile.Filename
-	audio, err := audioFile.Open()
+	if err := applyMetadataOverrides(c, meta); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	audio, err := source.Open()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open audio file"})
 		return
 	}
 	defer audio.Close()
 
-	audioURL, audioPublicID, err = utils.UploadToCloudinary(audio, audioFilePath)
+	audioInfo, err := utils.UploadReader(c.Request.Context(), audio, source.Size, "audio", source.Filename, source.ContentType)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload audio file"})
 		return
 	}
+	audioURL, audioPublicID = audioInfo.URL, audioInfo.Key
 
-	coverFile, coverErr := c.FormFile("coverFile")
-	if coverErr == nil {
-		coverFilePath := coverFile.Filename
-		cover, err := coverFile.Open()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open cover file"})
-			return
-		}
-		defer cover.Close()
-
-		coverURL, coverPublicID, err = utils.UploadToCloudinary(cover, coverFilePath)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload cover file"})
-			return
-		}
+	coverInfo, err := uploadAudioCover(c, meta)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload cover file"})
+		return
+	}
+	if coverInfo != nil {
+		coverURL, coverPublicID = coverInfo.URL, coverInfo.Key
 	}
 
 	newAudio := models.Audio{
 		Title:         title,
 		About:         about,
 		Category:      category,
-		Owner:         userModel.ID,
+		Visibility:    visibility,
+		OwnerID:       userModel.ID,
 		AudioURL:      audioURL,
 		CoverURL:      coverURL,
 		AudioPublicID: audioPublicID,
 		CoverPublicID: coverPublicID,
+		Duration:      uint(math.Round(meta.Duration)),
+		Artist:        meta.Artist,
+		Album:         meta.Album,
+		TrackNumber:   meta.TrackNumber,
+		Year:          meta.Year,
+		Genre:         meta.Genre,
 	}
 
-	if err := initializers.DB.Create(&newAudio).Error; err != nil {
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newAudio).Error; err != nil {
+			return err
+		}
+		return recordUpload(tx, &newAudio)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save audio"})
 		return
 	}
 
+	generateWaveformAsync(newAudio.ID, newAudio.AudioPublicID)
+	newAudio.WaveformStatus = models.WaveformPending
+	newAudio.AudioURL, newAudio.CoverURL = utils.FileURL(newAudio.AudioURL), utils.FileURL(newAudio.CoverURL)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Audio created successfully",
 		"audio":   newAudio,
@@ -95,7 +156,77 @@ func CreateAudio(c *gin.Context) {
 }
 
 /*
-*
+* uploadAudioCover stores the cover sent in the form, falling back to the artwork embedded in the audio file.
+* A failure to store embedded artwork is only logged; nil means the audio has no cover.
+ */
+func uploadAudioCover(c *gin.Context, meta *media.Metadata) (*storage.ObjectInfo, error) {
+	if coverFile, err := c.FormFile("coverFile"); err == nil {
+		return utils.UploadFile(c.Request.Context(), coverFile, "covers")
+	}
+	if meta.Picture == nil {
+		return nil, nil
+	}
+
+	picture := meta.Picture
+	coverInfo, err := utils.UploadReader(c.Request.Context(), bytes.NewReader(picture.Data), int64(len(picture.Data)), "covers", "cover."+picture.Ext, picture.MIMEType)
+	if err != nil {
+		log.Printf("Failed to upload embedded cover art: %v", err)
+		return nil, nil
+	}
+	return coverInfo, nil
+}
+
+/*
+* readAudioMetadata extracts embedded tags and duration from the uploaded file.
+* Unreadable files yield empty metadata so the upload itself is not rejected here.
+ */
+func readAudioMetadata(source audioSource) *media.Metadata {
+	file, err := source.Open()
+	if err != nil {
+		return &media.Metadata{}
+	}
+	defer file.Close()
+
+	meta, err := media.ExtractMetadata(file)
+	if err != nil {
+		log.Printf("Failed to read metadata of %s: %v", source.Filename, err)
+		return &media.Metadata{}
+	}
+	return meta
+}
+
+/*
+* applyMetadataOverrides replaces extracted values with the ones explicitly sent in the form
+ */
+func applyMetadataOverrides(c *gin.Context, meta *media.Metadata) error {
+	if artist := c.PostForm("artist"); artist != "" {
+		meta.Artist = artist
+	}
+	if album := c.PostForm("album"); album != "" {
+		meta.Album = album
+	}
+	if genre := c.PostForm("genre"); genre != "" {
+		meta.Genre = genre
+	}
+	if track := c.PostForm("trackNumber"); track != "" {
+		value, err := strconv.Atoi(track)
+		if err != nil || value < 0 {
+			return errors.New("invalid track number")
+		}
+		meta.TrackNumber = value
+	}
+	if year := c.PostForm("year"); year != "" {
+		value, err := strconv.Atoi(year)
+		if err != nil || value < 0 {
+			return errors.New("invalid year")
+		}
+		meta.Year = value
+	}
+	return nil
+}
+
+/*
+* This method updates the details and cover of an audio owned by the user
  */
 func UpdateAudio(c *gin.Context) {
 	user, exists := c.Get("user")
@@ -113,11 +244,18 @@ func UpdateAudio(c *gin.Context) {
 	audioId := c.Param("audioId")
 
 	updates := make(map[string]interface{})
-	for _, field := range []string{"name", "about", "category"} {
+	for _, field := range []string{"name", "about", "category", "artist", "album", "genre"} {
 		if value := c.PostForm(field); value != "" {
 			updates[field] = value
 		}
 	}
+	if visibility := c.PostForm("visibility"); visibility != "" {
+		if !isAudioVisibility(visibility) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be public or private"})
+			return
+		}
+		updates["visibility"] = visibility
+	}
 
 	var audio models.Audio
 	if err := initializers.DB.First(&audio, "id = ? AND owner = ?", audioId, userModel.ID).Error; err != nil {
@@ -128,26 +266,19 @@ func UpdateAudio(c *gin.Context) {
 	coverFile, _ := c.FormFile("coverFile")
 	if coverFile != nil {
 		if audio.CoverPublicID != "" {
-			if err := utils.DestroyImage(audio.CoverPublicID); err != nil {
+			if err := utils.DeleteFile(c.Request.Context(), audio.CoverPublicID); err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove existing cover image"})
 				return
 			}
 		}
 
-		file, err := coverFile.Open()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open cover file"})
-			return
-		}
-		defer file.Close()
-
-		coverURL, coverPublicID, err := utils.UploadToCloudinary(file, coverFile.Filename)
+		coverInfo, err := utils.UploadFile(c.Request.Context(), coverFile, "covers")
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload new cover image"})
 			return
 		}
-		updates["cover_url"] = coverURL
-		updates["cover_public_id"] = coverPublicID
+		updates["cover_url"] = coverInfo.URL
+		updates["cover_public_id"] = coverInfo.Key
 	}
 
 	if err := initializers.DB.Model(&audio).Updates(updates).Error; err != nil {
@@ -155,6 +286,11 @@ func UpdateAudio(c *gin.Context) {
 		return
 	}
 
+	if coverFile != nil {
+		refreshCoversWithAudio(audio.ID)
+	}
+	audio.AudioURL, audio.CoverURL = utils.FileURL(audio.AudioURL), utils.FileURL(audio.CoverURL)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Audio updated successfully",
 		"data":    audio,
@@ -162,17 +298,48 @@ func UpdateAudio(c *gin.Context) {
 }
 
 /*
-* List all audios for admin
+* viewerID returns the ID of the authenticated caller, or 0 for anonymous requests
+ */
+func viewerID(c *gin.Context) uint {
+	if user, exists := c.Get("user"); exists {
+		if userModel, ok := user.(*models.User); ok {
+			return userModel.ID
+		}
+	}
+	return 0
+}
+
+func audioCursor(audio models.Audio) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: audio.CreatedAt, ID: audio.ID}
+}
+
+/*
+* List the audios visible to the caller, newest first.
+* Supports 'limit' and 'cursor' query parameters.
  */
 func GetLatestAudios(c *gin.Context) {
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+
 	var audios []models.Audio
 
-	if result := initializers.DB.Find(&audios); result.Error != nil {
+	if result := initializers.DB.Scopes(page.Scope("created_at", "id"), visibleAudios(viewerID(c))).Find(&audios); result.Error != nil {
 		c.Error(result.Error)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"audios": audios})
+	audios, next := pagination.Trim(page, audios, audioCursor)
+
+	audioList, err := serializers.Audios(initializers.DB, audios, viewerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load audio details"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pagination.Envelope(audioList, next))
 }
 
 /*
@@ -181,28 +348,15 @@ func GetLatestAudios(c *gin.Context) {
 func GetLatestUploads(c *gin.Context) {
 	var audios []models.Audio
 
-	if err := initializers.DB.Order("created_at desc").Limit(12).Find(&audios).Error; err != nil {
+	if err := initializers.DB.Scopes(visibleAudios(viewerID(c))).Order("created_at desc").Limit(12).Find(&audios).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query latest uploads"})
 		return
 	}
 
-	audioList := make([]map[string]interface{}, len(audios))
-	for i, item := range audios {
-		owner := models.User{}
-		initializers.DB.First(&owner, item.Owner)
-
-		audioList[i] = map[string]interface{}{
-			"id":       item.ID,
-			"title":    item.Title,
-			"about":    item.About,
-			"category": item.Category,
-			"file":     item.AudioURL,
-			"poster":   item.CoverURL,
-			"owner": map[string]interface{}{
-				"name": owner.Name,
-				"id":   owner.ID,
-			},
-		}
+	audioList, err := serializers.Audios(initializers.DB, audios, viewerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load audio details"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"audios": audioList})
@@ -214,32 +368,24 @@ func GetLatestUploads(c *gin.Context) {
 func GetSuggestionsList(c *gin.Context) {
 	var audios []models.Audio
 
-	if err := initializers.DB.Order("RANDOM()").Limit(3).Find(&audios).Error; err != nil {
+	if err := initializers.DB.Scopes(visibleAudios(viewerID(c))).Order("RANDOM()").Limit(3).Find(&audios).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query random songs"})
 		return
 	}
 
-	audioList := make([]map[string]interface{}, len(audios))
-	for i, item := range audios {
-		owner := models.User{}
-		initializers.DB.First(&owner, item.Owner)
-
-		audioList[i] = map[string]interface{}{
-			"id":       item.ID,
-			"title":    item.Title,
-			"category": item.Category,
-			"file":     item.AudioURL,
-			"poster":   item.CoverURL,
-			"owner": map[string]interface{}{
-				"name": owner.Name,
-				"id":   owner.ID,
-			},
-		}
+	audioList, err := serializers.Audios(initializers.DB, audios, viewerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load audio details"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"audios": audioList})
 }
 
+/*
+* FilterByMood lists the audios of a category, newest first.
+* Supports 'category', 'limit' and 'cursor' query parameters.
+ */
 func FilterByMood(c *gin.Context) {
 	category := c.Query("category")
 
@@ -248,103 +394,98 @@ func FilterByMood(c *gin.Context) {
 		return
 	}
 
-	var audios []models.Audio
-
-	if err := initializers.DB.Order("created_at desc").Where("category = ?", category).Find(&audios).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query audios by category"})
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
 		return
 	}
 
-	if len(audios) == 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"message": "No audios found for the specified category.",
-			"audios":  []interface{}{},
-		})
+	var audios []models.Audio
+
+	if err := initializers.DB.Scopes(page.Scope("created_at", "id"), visibleAudios(viewerID(c))).Where("category = ?", category).Find(&audios).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query audios by category"})
 		return
 	}
 
-	audioList := make([]map[string]interface{}, len(audios))
-	for i, item := range audios {
-		owner := models.User{}
-		initializers.DB.First(&owner, item.Owner)
+	audios, next := pagination.Trim(page, audios, audioCursor)
 
-		audioList[i] = map[string]interface{}{
-			"id":       item.ID,
-			"title":    item.Title,
-			"category": item.Category,
-			"file":     item.AudioURL,
-			"poster":   item.CoverURL,
-			"owner": map[string]interface{}{
-				"name": owner.Name,
-				"id":   owner.ID,
-			},
-		}
+	audioList, err := serializers.Audios(initializers.DB, audios, viewerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load audio details"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"audios": audioList})
+	c.JSON(http.StatusOK, pagination.Envelope(audioList, next))
 }
 
+/*
+* GetUploadsById lists the uploads of a user, newest first. Private uploads are only listed to their owner.
+* Supports 'limit' and 'cursor' query parameters.
+ */
 func GetUploadsById(c *gin.Context) {
 	userId := c.Param("userId")
 
-	var audios []models.Audio
-
-	if err := initializers.DB.Where("owner = ?", userId).Find(&audios).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query user's uploads"})
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
 		return
 	}
 
-	if len(audios) == 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"message": "No uploads found for the specified user.",
-			"audios":  []interface{}{},
-		})
+	var audios []models.Audio
+
+	if err := initializers.DB.Scopes(page.Scope("created_at", "id"), visibleAudios(viewerID(c))).Where("owner = ?", userId).Find(&audios).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query user's uploads"})
 		return
 	}
 
-	audioList := make([]map[string]interface{}, len(audios))
-	for i, item := range audios {
-		owner := models.User{}
-		initializers.DB.First(&owner, item.Owner)
+	audios, next := pagination.Trim(page, audios, audioCursor)
 
-		audioList[i] = map[string]interface{}{
-			"id":       item.ID,
-			"title":    item.Title,
-			"category": item.Category,
-			"file":     item.AudioURL,
-			"poster":   item.CoverURL,
-			"owner": map[string]interface{}{
-				"name": owner.Name,
-				"id":   owner.ID,
-			},
-		}
+	audioList, err := serializers.Audios(initializers.DB, audios, viewerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load audio details"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"audios": audioList})
+	c.JSON(http.StatusOK, pagination.Envelope(audioList, next))
 }
 
-// search audios or artist
-func GeneralSearch(c *gin.Context) {
-	query := c.Query("q")
-
-	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query cannot be empty"})
+/*
+* StreamAudio serves the audio bytes from the storage backend.
+* Supports Range requests (206 Partial Content), ETag and conditional requests.
+* Audio that only exists as a remote URL, such as files uploaded to Cloudinary before the
+* current backend was configured, is redirected to that URL. Cloudinary audio is redirected to its
+* delivery URL too, which supports Range requests itself.
+* Private audios need their owner or a share link, see canViewAudio.
+ */
+func StreamAudio(c *gin.Context) {
+	audio, ok := findViewableAudio(c, c.Param("audioId"))
+	if !ok {
 		return
 	}
 
-	var artists []models.User
-	artistErr := initializers.DB.
-		Where("name LIKE ?", "%"+query+"%").
-		Find(&artists).Error
-
-	if artistErr != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed in artists", "details": artistErr.Error()})
+	_, cloudinaryBackend := initializers.Storage.(*storage.CloudinaryStorage)
+	if audio.AudioPublicID == "" || (cloudinaryBackend && audio.AudioURL != "") {
+		if audio.AudioURL == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Audio file not found"})
+			return
+		}
+		c.Redirect(http.StatusFound, audio.AudioURL)
 		return
 	}
 
-	results := gin.H{
-		"artists": artists,
+	object, err := initializers.Storage.Open(c.Request.Context(), audio.AudioPublicID)
+	if errors.Is(err, storage.ErrNotFound) {
+		if audio.AudioURL != "" {
+			c.Redirect(http.StatusFound, audio.AudioURL)
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Audio file not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open audio file"})
+		return
 	}
+	defer object.Close()
 
-	c.JSON(http.StatusOK, results)
+	serveObject(c, object)
 }
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+/*
+* canViewAudio is the read policy for audios. Public audios are readable by everyone, private (unreleased) ones
+* by their owner and anyone holding an active share link to them.
+ */
+func canViewAudio(audio *models.Audio, viewer uint, share shareCredentials) bool {
+	if audioListedFor(audio, viewer) {
+		return true
+	}
+	_, ok := findShareLink(models.ShareResourceAudio, audio.ID, share)
+	return ok
+}
+
+/*
+* findViewableAudio loads an audio the caller may read.
+* Missing and inaccessible audios both get a 404 so unreleased uploads are not revealed.
+ */
+func findViewableAudio(c *gin.Context, audioID interface{}) (*models.Audio, bool) {
+	var audio models.Audio
+	if err := initializers.DB.Where("id = ?", audioID).First(&audio).Error; err != nil ||
+		!canViewAudio(&audio, viewerID(c), requestShare(c)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Audio not found"})
+		return nil, false
+	}
+	return &audio, true
+}
+
+/*
+* visibleAudios limits an audio query to public audios and the viewer's own.
+* Listings use it; share links only open single audios.
+ */
+func visibleAudios(viewer uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(audios.visibility = ? OR audios.owner = ?)", models.AudioPublic, viewer)
+	}
+}
+
+/*
+* audioListedFor reports whether an audio shows up in listings for the viewer, the in-memory form of visibleAudios
+ */
+func audioListedFor(audio *models.Audio, viewer uint) bool {
+	return audio.Visibility == models.AudioPublic || (viewer != 0 && audio.OwnerID == viewer)
+}
+
+/*
+* filterVisibleAudios drops the private audios of other users from audios already loaded, such as playlist tracks
+ */
+func filterVisibleAudios(audios []models.Audio, viewer uint) []models.Audio {
+	visible := make([]models.Audio, 0, len(audios))
+	for i := range audios {
+		if audioListedFor(&audios[i], viewer) {
+			visible = append(visible, audios[i])
+		}
+	}
+	return visible
+}
+
+func isAudioVisibility(visibility string) bool {
+	for _, v := range models.AudioVisibilities {
+		if v == visibility {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"backend/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestFilterVisibleAudios(t *testing.T) {
+	audio := func(id, owner uint, visibility string) models.Audio {
+		a := models.Audio{OwnerID: owner, Visibility: visibility}
+		a.ID = id
+		return a
+	}
+	audios := []models.Audio{
+		audio(1, 10, models.AudioPublic),
+		audio(2, 10, models.AudioPrivate),
+		audio(3, 20, models.AudioPrivate),
+		audio(4, 20, models.AudioPublic),
+	}
+
+	tests := []struct {
+		name   string
+		viewer uint
+		want   []uint
+	}{
+		{"anonymous sees public audios", 0, []uint{1, 4}},
+		{"owner sees their unreleased audios", 10, []uint{1, 2, 4}},
+		{"other users do not", 30, []uint{1, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]uint, 0)
+			for _, a := range filterVisibleAudios(audios, tt.viewer) {
+				got = append(got, a.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterVisibleAudios() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/media"
+	"backend/internal/storage"
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// formContext builds a gin context for a multipart POST with the given fields and optional cover file.
+func formContext(t *testing.T, fields map[string]string, cover []byte) *gin.Context {
+	t.Helper()
+
+	body := new(bytes.Buffer)
+	form := multipart.NewWriter(body)
+	for name, value := range fields {
+		form.WriteField(name, value)
+	}
+	if cover != nil {
+		part, err := form.CreateFormFile("coverFile", "cover.jpg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(cover)
+	}
+	form.Close()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/audio", body)
+	c.Request.Header.Set("Content-Type", form.FormDataContentType())
+	return c
+}
+
+func TestApplyMetadataOverrides(t *testing.T) {
+	extracted := media.Metadata{Artist: "Tag Artist", Album: "Tag Album", Genre: "Tag Genre", TrackNumber: 3, Year: 1999}
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   media.Metadata
+		err    string
+	}{
+		{"no fields keep tags", nil, extracted, ""},
+		{"empty fields keep tags", map[string]string{"artist": "", "year": ""}, extracted, ""},
+		{
+			"fields override tags",
+			map[string]string{"artist": "Form Artist", "album": "Form Album", "genre": "Form Genre", "trackNumber": "7", "year": "2021"},
+			media.Metadata{Artist: "Form Artist", Album: "Form Album", Genre: "Form Genre", TrackNumber: 7, Year: 2021},
+			"",
+		},
+		{"partial override", map[string]string{"album": "Form Album"}, media.Metadata{Artist: "Tag Artist", Album: "Form Album", Genre: "Tag Genre", TrackNumber: 3, Year: 1999}, ""},
+		{"track not a number", map[string]string{"trackNumber": "three"}, media.Metadata{}, "invalid track number"},
+		{"negative track", map[string]string{"trackNumber": "-1"}, media.Metadata{}, "invalid track number"},
+		{"year not a number", map[string]string{"year": "last year"}, media.Metadata{}, "invalid year"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := extracted
+			err := applyMetadataOverrides(formContext(t, tt.fields, nil), &meta)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("applyMetadataOverrides() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyMetadataOverrides() error = %v", err)
+			}
+			if meta.Artist != tt.want.Artist || meta.Album != tt.want.Album || meta.Genre != tt.want.Genre ||
+				meta.TrackNumber != tt.want.TrackNumber || meta.Year != tt.want.Year {
+				t.Errorf("applyMetadataOverrides() = %+v, want %+v", meta, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadAudioCover(t *testing.T) {
+	previous := initializers.Storage
+	t.Cleanup(func() { initializers.Storage = previous })
+
+	embedded := &media.Picture{MIMEType: "image/png", Ext: "png", Data: []byte("\x89PNG embedded artwork")}
+	uploaded := []byte("uploaded cover bytes")
+
+	tests := []struct {
+		name    string
+		cover   []byte
+		picture *media.Picture
+		want    []byte
+		ext     string
+	}{
+		{"uploaded cover wins over artwork", uploaded, embedded, uploaded, ".jpg"},
+		{"embedded artwork fallback", nil, embedded, embedded.Data, ".png"},
+		{"no cover at all", nil, nil, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := storage.NewLocalStorage(t.TempDir(), "https://api.example.com", "test-secret")
+			initializers.Storage = local
+
+			info, err := uploadAudioCover(formContext(t, nil, tt.cover), &media.Metadata{Picture: tt.picture})
+			if err != nil {
+				t.Fatalf("uploadAudioCover() error = %v", err)
+			}
+			if tt.want == nil {
+				if info != nil {
+					t.Fatalf("uploadAudioCover() = %+v, want no cover", info)
+				}
+				return
+			}
+			if info == nil {
+				t.Fatal("uploadAudioCover() = nil, want a stored cover")
+			}
+			if !strings.HasPrefix(info.Key, "covers/") || !strings.HasSuffix(info.Key, tt.ext) {
+				t.Errorf("Key = %q, want covers/*%s", info.Key, tt.ext)
+			}
+
+			object, err := local.Open(context.Background(), info.Key)
+			if err != nil {
+				t.Fatalf("Open(%q) error = %v", info.Key, err)
+			}
+			defer object.Close()
+			data, _ := io.ReadAll(object)
+			if !bytes.Equal(data, tt.want) {
+				t.Errorf("stored cover = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
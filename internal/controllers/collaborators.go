@@ -0,0 +1,380 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/models"
+	"backend/internal/pagination"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+/*
+* playlistRole returns the role of a user on a playlist, or an empty string when they have none.
+* Pending invitations grant no role.
+ */
+func playlistRole(playlist *models.Playlist, userID uint) string {
+	if userID == 0 {
+		return ""
+	}
+	if playlist.Owner == userID {
+		return models.PlaylistRoleOwner
+	}
+
+	var collaborator models.PlaylistCollaborator
+	if err := initializers.DB.
+		Where("playlist_id = ? AND user_id = ? AND status = ?", playlist.ID, userID, models.InvitationAccepted).
+		First(&collaborator).Error; err != nil {
+		return ""
+	}
+	return collaborator.Role
+}
+
+/*
+* logPlaylistActivity appends an entry to the edit log of a playlist
+ */
+func logPlaylistActivity(tx *gorm.DB, playlistID, userID uint, action string, details gin.H) error {
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.PlaylistActivity{
+		PlaylistID: playlistID,
+		UserID:     userID,
+		Action:     action,
+		Details:    string(encoded),
+	}).Error
+}
+
+func collaboratorResponse(collaborator models.PlaylistCollaborator) gin.H {
+	return gin.H{
+		"user":        publicUser(collaborator.User),
+		"role":        collaborator.Role,
+		"status":      collaborator.Status,
+		"invited_at":  collaborator.CreatedAt,
+		"accepted_at": collaborator.AcceptedAt,
+	}
+}
+
+/*
+* InviteCollaborator invites a user to a playlist as a viewer or an editor.
+* It expects a JSON payload with 'userId' and 'role'. Inviting an existing collaborator changes their role.
+* Requires ownership of the playlist.
+ */
+func InviteCollaborator(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var req models.InviteCollaborator
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if validationErr := Validate.Struct(req); validationErr != nil {
+		c.Error(validationErr)
+		return
+	}
+
+	var playlist models.Playlist
+	if err := initializers.DB.Where("id = ? AND owner_id = ?", c.Param("playlistId"), userModel.ID).First(&playlist).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found or not owned by user"})
+		return
+	}
+
+	if req.UserID == userModel.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot invite yourself"})
+		return
+	}
+
+	var invitee models.User
+	if err := initializers.DB.Where("id = ? AND NOT banned", req.UserID).First(&invitee).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var collaborator models.PlaylistCollaborator
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("playlist_id = ? AND user_id = ?", playlist.ID, invitee.ID).First(&collaborator).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			collaborator = models.PlaylistCollaborator{
+				PlaylistID: playlist.ID,
+				UserID:     invitee.ID,
+				Role:       req.Role,
+				Status:     models.InvitationPending,
+				InvitedBy:  userModel.ID,
+			}
+			if err := tx.Create(&collaborator).Error; err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		} else if err := tx.Model(&collaborator).Update("role", req.Role).Error; err != nil {
+			return err
+		}
+
+		return logPlaylistActivity(tx, playlist.ID, userModel.ID, "invite_collaborator", gin.H{"userId": invitee.ID, "role": req.Role})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite collaborator"})
+		return
+	}
+
+	collaborator.User = invitee
+	c.JSON(http.StatusCreated, gin.H{"message": "Collaborator invited successfully", "collaborator": collaboratorResponse(collaborator)})
+}
+
+/*
+* AcceptInvitation lets the authenticated user join a playlist they were invited to
+ */
+func AcceptInvitation(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var collaborator models.PlaylistCollaborator
+	if err := initializers.DB.Where("playlist_id = ? AND user_id = ?", c.Param("playlistId"), userModel.ID).First(&collaborator).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	if collaborator.Status == models.InvitationAccepted {
+		c.JSON(http.StatusOK, gin.H{"message": "Invitation already accepted", "role": collaborator.Role})
+		return
+	}
+
+	now := time.Now()
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&collaborator).Updates(map[string]interface{}{
+			"status":      models.InvitationAccepted,
+			"accepted_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return logPlaylistActivity(tx, collaborator.PlaylistID, userModel.ID, "join", gin.H{"role": collaborator.Role})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation accepted", "role": collaborator.Role})
+}
+
+/*
+* RemoveCollaborator revokes a collaborator's access to a playlist.
+* Owners can remove anyone; collaborators can remove themselves to leave or decline an invitation.
+ */
+func RemoveCollaborator(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var playlist models.Playlist
+	if err := initializers.DB.First(&playlist, c.Param("playlistId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+		return
+	}
+
+	var collaborator models.PlaylistCollaborator
+	if err := initializers.DB.Where("playlist_id = ? AND user_id = ?", playlist.ID, c.Param("userId")).First(&collaborator).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Collaborator not found"})
+		return
+	}
+
+	if playlist.Owner != userModel.ID && collaborator.UserID != userModel.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the playlist owner can remove collaborators"})
+		return
+	}
+
+	action := "revoke_collaborator"
+	if collaborator.UserID == userModel.ID {
+		action = "leave"
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&collaborator).Error; err != nil {
+			return err
+		}
+		return logPlaylistActivity(tx, playlist.ID, userModel.ID, action, gin.H{"userId": collaborator.UserID})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove collaborator"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collaborator removed successfully"})
+}
+
+/*
+* ListCollaborators lists the collaborators and pending invitations of a playlist.
+* Requires ownership of, or a role on, the playlist.
+ */
+func ListCollaborators(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var playlist models.Playlist
+	if err := initializers.DB.First(&playlist, c.Param("playlistId")).Error; err != nil || playlistRole(&playlist, userModel.ID) == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+		return
+	}
+
+	var collaborators []models.PlaylistCollaborator
+	if err := initializers.DB.Preload("User").Where("playlist_id = ?", playlist.ID).Order("created_at").Find(&collaborators).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch collaborators"})
+		return
+	}
+
+	response := make([]gin.H, len(collaborators))
+	for i, collaborator := range collaborators {
+		response[i] = collaboratorResponse(collaborator)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"collaborators": response})
+}
+
+/*
+* ListInvitations lists the playlists the authenticated user has been invited to and not yet joined
+ */
+func ListInvitations(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var invitations []struct {
+		PlaylistID  uint
+		Title       string
+		Role        string
+		InvitedBy   uint
+		InviterName string
+		CreatedAt   time.Time
+	}
+
+	if err := initializers.DB.Table("playlist_collaborators pc").
+		Select("pc.playlist_id, p.title, pc.role, pc.invited_by, u.name AS inviter_name, pc.created_at").
+		Joins("JOIN playlists p ON p.id = pc.playlist_id AND p.deleted_at IS NULL").
+		Joins("LEFT JOIN users u ON u.id = pc.invited_by").
+		Where("pc.user_id = ? AND pc.status = ? AND pc.deleted_at IS NULL", userModel.ID, models.InvitationPending).
+		Order("pc.created_at DESC").
+		Scan(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	response := make([]gin.H, len(invitations))
+	for i, invitation := range invitations {
+		response[i] = gin.H{
+			"playlist_id": invitation.PlaylistID,
+			"title":       invitation.Title,
+			"role":        invitation.Role,
+			"invited_by":  gin.H{"id": invitation.InvitedBy, "name": invitation.InviterName},
+			"invited_at":  invitation.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": response})
+}
+
+/*
+* GetPlaylistActivity returns the edit log of a playlist, newest first.
+* Supports 'limit' and 'cursor' query parameters. Requires ownership of the playlist.
+ */
+func GetPlaylistActivity(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var playlist models.Playlist
+	if err := initializers.DB.Where("id = ? AND owner_id = ?", c.Param("playlistId"), userModel.ID).First(&playlist).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found or not owned by user"})
+		return
+	}
+
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+
+	var activities []models.PlaylistActivity
+	if err := initializers.DB.Preload("User").
+		Scopes(page.Scope("created_at", "id")).
+		Where("playlist_id = ?", playlist.ID).
+		Find(&activities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlist activity"})
+		return
+	}
+
+	activities, next := pagination.Trim(page, activities, func(activity models.PlaylistActivity) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: activity.CreatedAt, ID: activity.ID}
+	})
+
+	items := make([]gin.H, len(activities))
+	for i, activity := range activities {
+		items[i] = gin.H{
+			"id":         activity.ID,
+			"action":     activity.Action,
+			"details":    json.RawMessage(activity.Details),
+			"user":       publicUser(activity.User),
+			"created_at": activity.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, pagination.Envelope(items, next))
+}
@@ -3,13 +3,111 @@ package controllers
 import (
 	"backend/internal/initializers"
 	"backend/internal/models"
+	"backend/internal/pagination"
+	"backend/internal/serializers"
+	"backend/internal/utils"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
 )
 
 /*
-* This method add songs to the favorite
+* favoriteRequest reads the target of a favorite from the 'type' and 'targetId' form fields.
+* The older 'audioId' field is still accepted for audios.
+ */
+func favoriteRequest(c *gin.Context) (models.FavoriteRequest, bool) {
+	var req models.FavoriteRequest
+	if err := c.ShouldBind(&req); err != nil && c.PostForm("audioId") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required fields"})
+		return req, false
+	}
+
+	if audioID := c.PostForm("audioId"); audioID != "" {
+		id, err := strconv.ParseUint(audioID, 10, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid audioId"})
+			return req, false
+		}
+		req = models.FavoriteRequest{Type: models.FavoriteAudio, TargetID: uint(id)}
+	}
+	if req.Type == "" {
+		req.Type = models.FavoriteAudio
+	}
+
+	if validationErr := Validate.Struct(req); validationErr != nil {
+		c.Error(validationErr)
+		return req, false
+	}
+	return req, true
+}
+
+/*
+* checkFavoriteTarget verifies that the user may favorite the target, responding with an error otherwise.
+* Audios and playlists must be readable by the user, and users cannot favorite themselves.
+ */
+func checkFavoriteTarget(c *gin.Context, userModel *models.User, targetType string, targetID uint) bool {
+	switch targetType {
+	case models.FavoriteAudio:
+		if _, ok := findViewableAudio(c, targetID); !ok {
+			return false
+		}
+
+	case models.FavoritePlaylist:
+		if _, ok := findViewablePlaylist(c, targetID); !ok {
+			return false
+		}
+
+	case models.FavoriteUser:
+		if targetID == userModel.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot favorite yourself"})
+			return false
+		}
+		var target models.User
+		if err := initializers.DB.Where("id = ? AND NOT banned", targetID).First(&target).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return false
+		}
+	}
+	return true
+}
+
+/*
+* favoriteStatus returns how many users favorited an item and whether the viewer is one of them
+ */
+func favoriteStatus(targetType string, targetID, viewer uint) (int64, bool) {
+	var status struct {
+		LikeCount   int64
+		IsFavorited bool
+	}
+	initializers.DB.Raw(`SELECT count(*) AS like_count, coalesce(bool_or(user_id = ?), false) AS is_favorited
+		FROM favorites WHERE target_type = ? AND target_id = ?`, viewer, targetType, targetID).Scan(&status)
+	return status.LikeCount, status.IsFavorited
+}
+
+/*
+* insertFavorite adds a favorite unless it already exists. The primary key makes concurrent inserts safe.
+ */
+func insertFavorite(userID uint, targetType string, targetID uint) (bool, error) {
+	result := initializers.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Favorite{UserID: userID, TargetType: targetType, TargetID: targetID})
+	return result.RowsAffected > 0, result.Error
+}
+
+/*
+* deleteFavorite removes a favorite and reports whether it existed
+ */
+func deleteFavorite(userID uint, targetType string, targetID uint) (bool, error) {
+	result := initializers.DB.
+		Where("user_id = ? AND target_type = ? AND target_id = ?", userID, targetType, targetID).
+		Delete(&models.Favorite{})
+	return result.RowsAffected > 0, result.Error
+}
+
+/*
+* This method adds an audio, playlist or user to the favorites of the user.
+* It expects form data with 'type' (audio, playlist or user, default audio) and 'targetId', or 'audioId'.
  */
 func AddToFavorite(c *gin.Context) {
 	user, exists := c.Get("user")
@@ -24,33 +122,23 @@ func AddToFavorite(c *gin.Context) {
 		return
 	}
 
-	audioID := c.PostForm("audioId")
-	if audioID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required fields"})
+	req, ok := favoriteRequest(c)
+	if !ok {
 		return
 	}
 
-	var audio models.Audio
-	if err := initializers.DB.Where("id = ?", audioID).First(&audio).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Audio not found"})
+	if !checkFavoriteTarget(c, userModel, req.Type, req.TargetID) {
 		return
 	}
 
-	var count int64
-	err := initializers.DB.Model(&models.Favorite{}).Where("user_id = ? AND audio_id = ?", userModel.ID, audio.ID).Count(&count).Error
+	created, err := insertFavorite(userModel.ID, req.Type, req.TargetID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query favorites"})
-		return
-	}
-
-	if count > 0 {
-		c.JSON(http.StatusConflict, gin.H{"error": "Audio already in favorite"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to favorites"})
 		return
 	}
 
-	newFavorite := models.Favorite{UserID: userModel.ID, AudioID: audio.ID}
-	if err := initializers.DB.Create(&newFavorite).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to favorites"})
+	if !created {
+		c.JSON(http.StatusConflict, gin.H{"error": "Already in favorites"})
 		return
 	}
 
@@ -58,7 +146,8 @@ func AddToFavorite(c *gin.Context) {
 }
 
 /*
-* This method remove song from favorite list
+* This method removes an audio, playlist or user from the favorites of the user.
+* It expects the same form data as AddToFavorite.
  */
 func DeleteFromFavorite(c *gin.Context) {
 	user, exists := c.Get("user")
@@ -73,22 +162,28 @@ func DeleteFromFavorite(c *gin.Context) {
 		return
 	}
 
-	audioID := c.PostForm("audioId")
-	if audioID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required fields"})
+	req, ok := favoriteRequest(c)
+	if !ok {
 		return
 	}
 
-	if err := initializers.DB.Where("user_id = ? AND audio_id = ?", userModel.ID, audioID).Delete(&models.Favorite{}).Error; err != nil {
+	removed, err := deleteFavorite(userModel.ID, req.Type, req.TargetID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove from favorites"})
 		return
 	}
 
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not in favorites"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Removed from favorites successfully"})
 }
 
 /*
-* This method fetches favorite audios of an user
+* This method fetches the favorites of an user, most recently liked first.
+* Supports 'type' (audio, playlist or user, default audio), 'limit' and 'cursor' query parameters.
  */
 func GetAllFavorites(c *gin.Context) {
 	user, exists := c.Get("user")
@@ -103,33 +198,240 @@ func GetAllFavorites(c *gin.Context) {
 		return
 	}
 
+	targetType := c.DefaultQuery("type", models.FavoriteAudio)
+	if targetType != models.FavoriteAudio && targetType != models.FavoritePlaylist && targetType != models.FavoriteUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be audio, playlist or user"})
+		return
+	}
+
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+
 	var favorites []models.Favorite
-	if err := initializers.DB.Preload("Audio").Where("user_id = ?", userModel.ID).Find(&favorites).Error; err != nil {
+	if err := initializers.DB.
+		Scopes(page.Scope("created_at", "target_id")).
+		Where("user_id = ? AND target_type = ?", userModel.ID, targetType).
+		Find(&favorites).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve favorites"})
 		return
 	}
 
-	audioList := make([]map[string]interface{}, 0)
-	for _, fav := range favorites {
-		var owner models.User
-		if err := initializers.DB.First(&owner, fav.Audio.Owner).Error; err != nil {
-			continue
+	favorites, next := pagination.Trim(page, favorites, func(favorite models.Favorite) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: favorite.CreatedAt, ID: favorite.TargetID}
+	})
+
+	targetIDs := make([]uint, len(favorites))
+	for i, favorite := range favorites {
+		targetIDs[i] = favorite.TargetID
+	}
+
+	// Favorites of items that have since been deleted or hidden are skipped.
+	switch targetType {
+	case models.FavoriteAudio:
+		var found []models.Audio
+		if err := initializers.DB.Scopes(visibleAudios(userModel.ID)).Where("id IN ?", targetIDs).Find(&found).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve favorites"})
+			return
 		}
+		byID := make(map[uint]models.Audio, len(found))
+		for _, audio := range found {
+			byID[audio.ID] = audio
+		}
+
+		audios := make([]models.Audio, 0, len(favorites))
+		for _, id := range targetIDs {
+			if audio, ok := byID[id]; ok {
+				audios = append(audios, audio)
+			}
+		}
+
+		audioList, err := serializers.Audios(initializers.DB, audios, userModel.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load audio details"})
+			return
+		}
+		c.JSON(http.StatusOK, pagination.Envelope(audioList, next))
+
+	case models.FavoritePlaylist:
+		var found []models.Playlist
+		if err := initializers.DB.Where("id IN ?", targetIDs).Find(&found).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve favorites"})
+			return
+		}
+		byID := make(map[uint]models.Playlist, len(found))
+		for _, playlist := range found {
+			byID[playlist.ID] = playlist
+		}
+
+		response := make([]gin.H, 0, len(favorites))
+		for _, favorite := range favorites {
+			playlist, ok := byID[favorite.TargetID]
+			if !ok || !canViewPlaylist(&playlist, userModel.ID, shareCredentials{}) {
+				continue
+			}
+			response = append(response, gin.H{
+				"id":         playlist.ID,
+				"title":      playlist.Title,
+				"visibility": playlist.Visibility,
+				"coverurl":   utils.FileURL(playlist.DisplayCoverURL()),
+				"owner_id":   playlist.Owner,
+				"liked_at":   favorite.CreatedAt,
+			})
+		}
+		c.JSON(http.StatusOK, pagination.Envelope(response, next))
 
-		audioInfo := map[string]interface{}{
-			"id":       fav.Audio.ID,
-			"title":    fav.Audio.Title,
-			"about":    fav.Audio.About,
-			"category": fav.Audio.Category,
-			"file":     fav.Audio.AudioURL,
-			"poster":   fav.Audio.CoverURL,
-			"owner": map[string]interface{}{
-				"id":   owner.ID,
-				"name": owner.Name,
-			},
+	case models.FavoriteUser:
+		var found []models.User
+		if err := initializers.DB.Where("id IN ? AND NOT banned", targetIDs).Find(&found).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve favorites"})
+			return
 		}
-		audioList = append(audioList, audioInfo)
+		byID := make(map[uint]models.User, len(found))
+		for _, user := range found {
+			byID[user.ID] = user
+		}
+
+		response := make([]gin.H, 0, len(favorites))
+		for _, favorite := range favorites {
+			if user, ok := byID[favorite.TargetID]; ok {
+				entry := publicUser(user)
+				entry["liked_at"] = favorite.CreatedAt
+				response = append(response, entry)
+			}
+		}
+		c.JSON(http.StatusOK, pagination.Envelope(response, next))
+	}
+}
+
+/*
+* parseAudioID reads the 'audioId' path parameter
+ */
+func parseAudioID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("audioId"), 10, 0)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid audioId"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+/*
+* PutFavorite marks an audio as favorite. Repeating the request has no further effect.
+* Responds 201 when the favorite was created and 200 when it already existed.
+ */
+func PutFavorite(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	audioID, ok := parseAudioID(c)
+	if !ok {
+		return
+	}
+
+	if !checkFavoriteTarget(c, userModel, models.FavoriteAudio, audioID) {
+		return
+	}
+
+	created, err := insertFavorite(userModel.ID, models.FavoriteAudio, audioID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to favorites"})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	likeCount, _ := favoriteStatus(models.FavoriteAudio, audioID, userModel.ID)
+	c.JSON(status, gin.H{"audio_id": audioID, "is_favorited": true, "like_count": likeCount})
+}
+
+/*
+* RemoveFavorite unmarks an audio as favorite. Removing an audio that is not a favorite succeeds as well.
+ */
+func RemoveFavorite(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	audioID, ok := parseAudioID(c)
+	if !ok {
+		return
+	}
+
+	if _, err := deleteFavorite(userModel.ID, models.FavoriteAudio, audioID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove from favorites"})
+		return
+	}
+
+	likeCount, _ := favoriteStatus(models.FavoriteAudio, audioID, userModel.ID)
+	c.JSON(http.StatusOK, gin.H{"audio_id": audioID, "is_favorited": false, "like_count": likeCount})
+}
+
+/*
+* GetFavoriteStatuses reports which of up to 100 audios are favorites of the user.
+* It expects a JSON payload with 'audioIds'.
+ */
+func GetFavoriteStatuses(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var req models.FavoriteStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if validationErr := Validate.Struct(req); validationErr != nil {
+		c.Error(validationErr)
+		return
+	}
+
+	var favorited []uint
+	if err := initializers.DB.Model(&models.Favorite{}).
+		Where("user_id = ? AND target_type = ? AND target_id IN ?", userModel.ID, models.FavoriteAudio, req.AudioIDs).
+		Pluck("target_id", &favorited).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query favorites"})
+		return
+	}
+
+	statuses := make(map[uint]bool, len(req.AudioIDs))
+	for _, id := range req.AudioIDs {
+		statuses[id] = false
+	}
+	for _, id := range favorited {
+		statuses[id] = true
 	}
 
-	c.JSON(http.StatusOK, gin.H{"favorites": audioList, "owner_id": userModel.ID})
+	c.JSON(http.StatusOK, gin.H{"favorites": statuses})
 }
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/models"
+	"backend/internal/pagination"
+	"backend/internal/serializers"
+	"backend/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// feedTrackPreview is how many of the added tracks a tracks_added item carries.
+const feedTrackPreview = 5
+
+/*
+* recordUpload adds a new audio to the feed of the uploader's followers
+ */
+func recordUpload(tx *gorm.DB, audio *models.Audio) error {
+	return tx.Create(&models.Activity{
+		ActorID: audio.OwnerID,
+		Action:  models.ActivityUpload,
+		AudioID: &audio.ID,
+	}).Error
+}
+
+/*
+* recordPlaylistPublished adds a playlist to the feed the first time it becomes public
+ */
+func recordPlaylistPublished(tx *gorm.DB, playlist *models.Playlist, actorID uint) error {
+	if playlist.Visibility != models.PlaylistPublic {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(&models.Activity{}).
+		Where("playlist_id = ? AND action = ?", playlist.ID, models.ActivityPlaylistCreated).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return tx.Create(&models.Activity{
+		ActorID:    actorID,
+		Action:     models.ActivityPlaylistCreated,
+		PlaylistID: &playlist.ID,
+	}).Error
+}
+
+/*
+* recordTracksAdded adds tracks added to a public playlist to the feed of the actor's followers
+ */
+func recordTracksAdded(tx *gorm.DB, playlist *models.Playlist, actorID uint, audioIDs []uint) error {
+	if playlist.Visibility != models.PlaylistPublic || len(audioIDs) == 0 {
+		return nil
+	}
+
+	ids := make(models.JSONIntegerArray, len(audioIDs))
+	for i, id := range audioIDs {
+		ids[i] = int(id)
+	}
+
+	return tx.Create(&models.Activity{
+		ActorID:    actorID,
+		Action:     models.ActivityTracksAdded,
+		PlaylistID: &playlist.ID,
+		AudioIDs:   ids,
+	}).Error
+}
+
+/*
+* addedIDs returns the requested audios that were not skipped as duplicates
+ */
+func addedIDs(requested, skipped []uint) []uint {
+	skip := make(map[uint]bool, len(skipped))
+	for _, id := range skipped {
+		skip[id] = true
+	}
+
+	added := make([]uint, 0, len(requested))
+	for _, id := range uniqueIDs(requested) {
+		if !skip[id] {
+			added = append(added, id)
+		}
+	}
+	return added
+}
+
+func activityCursor(activity models.Activity) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: activity.CreatedAt, ID: activity.ID}
+}
+
+/*
+* GetFeed lists uploads, new public playlists and tracks added to public playlists by the users
+* the caller follows, newest first. Playlists that are no longer public and deleted audios are left out.
+* Supports 'limit' and 'cursor' query parameters. Requires user authentication.
+ */
+func GetFeed(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+
+	followings := initializers.DB.Model(&models.User_Relations{}).Select("following_id").Where("follower_id = ?", userModel.ID)
+	publicPlaylists := initializers.DB.Model(&models.Playlist{}).Select("id").Where("visibility = ?", models.PlaylistPublic)
+	audios := initializers.DB.Model(&models.Audio{}).Select("id").Where("visibility = ?", models.AudioPublic)
+
+	var activities []models.Activity
+	if err := initializers.DB.Preload("Actor").
+		Scopes(page.Scope("created_at", "id")).
+		Where("actor_id IN (?)", followings).
+		Where("playlist_id IS NULL OR playlist_id IN (?)", publicPlaylists).
+		Where("audio_id IS NULL OR audio_id IN (?)", audios).
+		Find(&activities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch feed"})
+		return
+	}
+
+	activities, next := pagination.Trim(page, activities, activityCursor)
+
+	audioIDs := make([]uint, 0, len(activities))
+	playlistIDs := make([]uint, 0, len(activities))
+	for _, activity := range activities {
+		if activity.AudioID != nil {
+			audioIDs = append(audioIDs, *activity.AudioID)
+		}
+		if activity.PlaylistID != nil {
+			playlistIDs = append(playlistIDs, *activity.PlaylistID)
+		}
+		for i, id := range activity.AudioIDs {
+			if i == feedTrackPreview {
+				break
+			}
+			audioIDs = append(audioIDs, uint(id))
+		}
+	}
+
+	var foundAudios []models.Audio
+	if err := initializers.DB.Scopes(visibleAudios(userModel.ID)).Where("id IN ?", uniqueIDs(audioIDs)).Find(&foundAudios).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch feed"})
+		return
+	}
+
+	audioList, err := serializers.Audios(initializers.DB, foundAudios, userModel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load audio details"})
+		return
+	}
+
+	audiosByID := make(map[uint]serializers.Audio, len(audioList))
+	for _, audio := range audioList {
+		audiosByID[audio.ID] = audio
+	}
+
+	var playlists []models.Playlist
+	if err := initializers.DB.Where("id IN ?", uniqueIDs(playlistIDs)).Find(&playlists).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch feed"})
+		return
+	}
+
+	playlistsByID := make(map[uint]gin.H, len(playlists))
+	for _, playlist := range playlists {
+		playlistsByID[playlist.ID] = gin.H{
+			"id":       playlist.ID,
+			"title":    playlist.Title,
+			"coverurl": utils.FileURL(playlist.DisplayCoverURL()),
+		}
+	}
+
+	items := make([]gin.H, 0, len(activities))
+	for _, activity := range activities {
+		item := gin.H{
+			"id":         activity.ID,
+			"type":       activity.Action,
+			"actor":      publicUser(activity.Actor),
+			"created_at": activity.CreatedAt,
+		}
+
+		if activity.AudioID != nil {
+			audio, ok := audiosByID[*activity.AudioID]
+			if !ok {
+				continue
+			}
+			item["audio"] = audio
+		}
+
+		if activity.PlaylistID != nil {
+			playlist, ok := playlistsByID[*activity.PlaylistID]
+			if !ok {
+				continue
+			}
+			item["playlist"] = playlist
+		}
+
+		if activity.Action == models.ActivityTracksAdded {
+			tracks := make([]serializers.Audio, 0, feedTrackPreview)
+			for i, id := range activity.AudioIDs {
+				if i == feedTrackPreview {
+					break
+				}
+				if audio, ok := audiosByID[uint(id)]; ok {
+					tracks = append(tracks, audio)
+				}
+			}
+			if len(tracks) == 0 {
+				continue
+			}
+			item["audios"] = tracks
+			item["track_count"] = len(activity.AudioIDs)
+		}
+
+		items = append(items, item)
+	}
+
+	c.JSON(http.StatusOK, pagination.Envelope(items, next))
+}
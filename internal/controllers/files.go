@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/storage"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+* ServeFile serves files kept by the local storage backend.
+* Every request needs an unexpired signature from SignedURL; other backends serve their own URLs.
+ */
+func ServeFile(c *gin.Context) {
+	local, ok := initializers.Storage.(*storage.LocalStorage)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	if err := local.VerifySignature(key, c.Query("expires"), c.Query("signature")); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired link"})
+		return
+	}
+
+	object, err := local.Open(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+		return
+	}
+	defer object.Close()
+
+	serveObject(c, object)
+}
+
+/*
+* serveObject writes a stored object with Range, ETag and conditional request support
+ */
+func serveObject(c *gin.Context, object *storage.Object) {
+	if object.ContentType != "" {
+		c.Header("Content-Type", object.ContentType)
+	}
+	if object.ETag != "" {
+		c.Header("ETag", object.ETag)
+	}
+	c.Header("Cache-Control", "private, max-age=3600")
+
+	http.ServeContent(c.Writer, c.Request, "", object.ModTime, object)
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/models"
+	"backend/internal/pagination"
+	"backend/internal/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+* RecordPlay stores a play event for the authenticated user.
+* Nothing is stored while the user has history collection paused.
+ */
+func RecordPlay(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var req models.RecordPlay
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if validationErr := Validate.Struct(req); validationErr != nil {
+		c.Error(validationErr)
+		return
+	}
+
+	if userModel.HistoryPaused {
+		c.JSON(http.StatusOK, gin.H{"message": "History is paused, play not recorded", "recorded": false})
+		return
+	}
+
+	audio, ok := findViewableAudio(c, req.AudioID)
+	if !ok {
+		return
+	}
+
+	entry := models.History{
+		Owner:            userModel.ID,
+		AudioID:          audio.ID,
+		Position:         req.Position,
+		DurationListened: req.DurationListened,
+		Client:           req.Client,
+		PlayedAt:         time.Now(),
+	}
+
+	if err := initializers.DB.Create(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record play"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Play recorded", "recorded": true, "id": entry.ID})
+}
+
+/*
+* GetHistory lists the authenticated user's most recent plays.
+* Supports 'limit' and 'cursor' query parameters.
+ */
+func GetHistory(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+
+	var entries []models.History
+	if err := initializers.DB.Preload("Audio").
+		Scopes(page.Scope("played_at", "id")).
+		Where("owner_id = ?", userModel.ID).
+		Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch history"})
+		return
+	}
+
+	entries, next := pagination.Trim(page, entries, func(entry models.History) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: entry.PlayedAt, ID: entry.ID}
+	})
+
+	history := make([]gin.H, len(entries))
+	for i, entry := range entries {
+		history[i] = gin.H{
+			"id":                entry.ID,
+			"played_at":         entry.PlayedAt,
+			"position":          entry.Position,
+			"duration_listened": entry.DurationListened,
+			"client":            entry.Client,
+			"audio": gin.H{
+				"id":       entry.Audio.ID,
+				"title":    entry.Audio.Title,
+				"category": entry.Audio.Category,
+				"file":     utils.FileURL(entry.Audio.AudioURL),
+				"poster":   utils.FileURL(entry.Audio.CoverURL),
+			},
+		}
+	}
+
+	response := pagination.Envelope(history, next)
+	response["paused"] = userModel.HistoryPaused
+	c.JSON(http.StatusOK, response)
+}
+
+/*
+* DeleteHistoryEntry permanently removes a single play from the authenticated user's history
+ */
+func DeleteHistoryEntry(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	historyID := c.Param("historyId")
+
+	result := initializers.DB.Unscoped().Where("id = ? AND owner_id = ?", historyID, userModel.ID).Delete(&models.History{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete history entry"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "History entry not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "History entry deleted successfully"})
+}
+
+/*
+* ClearHistory permanently removes every play from the authenticated user's history
+ */
+func ClearHistory(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	result := initializers.DB.Unscoped().Where("owner_id = ?", userModel.ID).Delete(&models.History{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "History cleared successfully", "deleted": result.RowsAffected})
+}
+
+/*
+* UpdateHistorySettings pauses or resumes history collection for the authenticated user.
+* It expects a JSON payload with 'paused'.
+ */
+func UpdateHistorySettings(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var payload struct {
+		Paused *bool `json:"paused"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.Paused == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if err := initializers.DB.Model(&models.User{}).Where("id = ?", userModel.ID).UpdateColumn("history_paused", *payload.Paused).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update history settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "History settings updated", "paused": *payload.Paused})
+}
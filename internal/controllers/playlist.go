@@ -3,14 +3,20 @@ package controllers
 import (
 	"backend/internal/initializers"
 	"backend/internal/models"
+	"backend/internal/pagination"
+	"backend/internal/serializers"
+	"backend/internal/utils"
+	"encoding/json"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 /*
 * CreatePlaylist creates a new playlist with a given title and visibility.
-* It expects form data containing 'title' and 'visibility' fields.
+* It expects form data containing 'title' and 'visibility' fields, and a JSON 'rules' field
+* when the visibility is "auto".
 * Requires user authentication.
  */
 func CreatePlaylist(c *gin.Context) {
@@ -34,13 +40,44 @@ func CreatePlaylist(c *gin.Context) {
 		return
 	}
 
+	if !isPlaylistVisibility(visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be public, private, unlisted or auto"})
+		return
+	}
+
 	newPlaylist := models.Playlist{
 		Title:      title,
 		Owner:      userModel.ID,
 		Visibility: visibility,
 	}
 
-	if err := initializers.DB.Create(&newPlaylist).Error; err != nil {
+	if visibility == models.PlaylistAuto {
+		var rules models.SmartPlaylistRules
+		if err := json.Unmarshal([]byte(c.PostForm("rules")), &rules); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Smart playlists require valid rules"})
+			return
+		}
+		if err := rules.Normalize(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		newPlaylist.Rules = &rules
+	}
+
+	var shareToken *string
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newPlaylist).Error; err != nil {
+			return err
+		}
+		if err := recordPlaylistPublished(tx, &newPlaylist, userModel.ID); err != nil {
+			return err
+		}
+
+		var err error
+		shareToken, err = ensureShareToken(tx, &newPlaylist)
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create playlist"})
 		return
 	}
@@ -48,10 +85,12 @@ func CreatePlaylist(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Playlist created successfully",
 		"playlist": gin.H{
-			"id":         newPlaylist.ID,
-			"title":      newPlaylist.Title,
-			"visibility": newPlaylist.Visibility,
-			"songs":      newPlaylist.Audios,
+			"id":          newPlaylist.ID,
+			"title":       newPlaylist.Title,
+			"visibility":  newPlaylist.Visibility,
+			"rules":       newPlaylist.Rules,
+			"share_token": shareToken,
+			"songs":       newPlaylist.Audios,
 		},
 	})
 }
@@ -59,7 +98,7 @@ func CreatePlaylist(c *gin.Context) {
 /*
 * AddToPlaylist adds an existing audio track to a specified playlist.
 * It expects form data with 'audioId' and 'playlistId'.
-* Requires user authentication and ownership of the playlist.
+* Requires user authentication and ownership of, or editor access to, the playlist.
  */
 func AddToPlaylist(c *gin.Context) {
 	user, exists := c.Get("user")
@@ -83,73 +122,110 @@ func AddToPlaylist(c *gin.Context) {
 	}
 
 	var audio models.Audio
-	if err := initializers.DB.Where("id = ?", audioID).First(&audio).Error; err != nil {
+	if err := initializers.DB.Scopes(visibleAudios(userModel.ID)).Where("id = ?", audioID).First(&audio).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Audio not found"})
 		return
 	}
 
-	var playlist models.Playlist
-	if err := initializers.DB.Where("id = ? AND owner_id = ?", playlistID, userModel.ID).First(&playlist).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found or not owned by user"})
+	playlist, ok := findEditablePlaylist(c, playlistID, userModel)
+	if !ok {
 		return
 	}
 
-	var existingAudios []models.Audio
-	if err := initializers.DB.Model(&playlist).Association("Audios").Find(&existingAudios, "id = ?", audioID); err == nil && len(existingAudios) > 0 {
+	var existing int64
+	if err := initializers.DB.Model(&models.PlaylistAudio{}).Where("playlist_id = ? AND audio_id = ?", playlist.ID, audio.ID).Count(&existing).Error; err == nil && existing > 0 {
 		c.JSON(http.StatusConflict, gin.H{"error": "Audio already in playlist"})
 		return
 	}
 
-	if err := initializers.DB.Model(&playlist).Association("Audios").Append(&audio); err != nil {
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := bumpPlaylistVersion(tx, playlist, nil); err != nil {
+			return err
+		}
+		if _, err := insertPlaylistTracks(tx, playlist.ID, []uint{audio.ID}, nil, userModel.ID); err != nil {
+			return err
+		}
+		if err := recordTracksAdded(tx, playlist, userModel.ID, []uint{audio.ID}); err != nil {
+			return err
+		}
+		return logPlaylistActivity(tx, playlist.ID, userModel.ID, "add_tracks", gin.H{"audioIds": []uint{audio.ID}})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add audio to playlist"})
 		return
 	}
 
+	refreshPlaylistCoverAsync(playlist.ID)
+
 	c.JSON(http.StatusCreated, gin.H{"message": "Audio added to playlist successfully"})
 }
 
 /*
 * GetPlaylistDetailsByID retrieves details of a playlist by its ID.
 * It uses a path parameter 'playlistId' to identify the playlist.
-* No authentication required to view public playlists.
+* No authentication required to view public playlists, or unlisted ones with their share token.
  */
 func GetPlaylistDetailsByID(c *gin.Context) {
-	playlistID := c.Param("playlistId")
-
-	var playlist models.Playlist
 	var owner models.User
 
-	if err := initializers.DB.Preload("Audios").Where("id = ?", playlistID).First(&playlist).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+	playlist, ok := findViewablePlaylist(c, c.Param("playlistId"))
+	if !ok {
 		return
 	}
 
-	playlist.SetRandomCoverURL(initializers.DB)
-
-	if err := initializers.DB.Model(&playlist).Update("cover_url", playlist.CoverURL).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update playlist cover"})
+	audios, err := playlistTrackAudios(initializers.DB, playlist)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlist tracks"})
 		return
 	}
 
+	// Smart playlists change without edits, and older playlists may predate generated covers.
+	if mosaicOutdated(playlist, filterVisibleAudios(audios, 0)) {
+		refreshPlaylistCoverAsync(playlist.ID)
+	}
+	audios = filterVisibleAudios(audios, viewerID(c))
+
 	if err := initializers.DB.Where("id = ?", playlist.Owner).First(&owner).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Owner not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	var forkCount int64
+	if err := initializers.DB.Model(&models.Playlist{}).Where("forked_from_id = ?", playlist.ID).Count(&forkCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count playlist forks"})
+		return
+	}
+
+	likeCount, isFavorited := favoriteStatus(models.FavoritePlaylist, playlist.ID, viewerID(c))
+
+	response := gin.H{
 		"playlist": gin.H{
-			"id":         playlist.ID,
-			"title":      playlist.Title,
-			"visibility": playlist.Visibility,
-			"coverurl":   playlist.CoverURL,
-			"owner_name": owner.Name,
-			"owner_id":   owner.ID,
-			"song_count": len(playlist.Audios),
+			"id":           playlist.ID,
+			"title":        playlist.Title,
+			"visibility":   playlist.Visibility,
+			"coverurl":     utils.FileURL(playlist.DisplayCoverURL()),
+			"custom_cover": playlist.CustomCover,
+			"owner_name":   owner.Name,
+			"owner_id":     owner.ID,
+			"song_count":   len(audios),
+			"fork_count":   forkCount,
+			"like_count":   likeCount,
+			"is_favorited": isFavorited,
+			"forked_from":  forkedFrom(playlist, viewerID(c)),
 		},
-	})
+	}
+
+	if playlist.Owner == viewerID(c) && playlist.Visibility == models.PlaylistUnlisted {
+		if token, err := unlistedShareToken(initializers.DB, playlist.ID); err == nil && token != nil {
+			response["playlist"].(gin.H)["share_token"] = *token
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
-// Query public playlists not owned by the current user that have at least one song
+// Query public playlists not owned by the current user that have at least one song, newest first.
+// Supports 'limit' and 'cursor' query parameters.
 func GetPublicPlaylists(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
@@ -163,12 +239,19 @@ func GetPublicPlaylists(c *gin.Context) {
 		return
 	}
 
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+
 	var playlists []models.Playlist
 
-	err := initializers.DB.
+	err = initializers.DB.
 		Joins("JOIN playlist_audios ON playlist_audios.playlist_id = playlists.id").
 		Joins("JOIN audios ON audios.id = playlist_audios.audio_id AND audios.deleted_at IS NULL").
 		Where("playlists.visibility = 'public' AND playlists.owner_id <> ?", userModel.ID).
+		Scopes(page.Scope("playlists.created_at", "playlists.id")).
 		Group("playlists.id").
 		Having("COUNT(audios.id) > 0").
 		Find(&playlists).Error
@@ -178,6 +261,10 @@ func GetPublicPlaylists(c *gin.Context) {
 		return
 	}
 
+	playlists, next := pagination.Trim(page, playlists, func(playlist models.Playlist) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: playlist.CreatedAt, ID: playlist.ID}
+	})
+
 	response := make([]gin.H, 0)
 
 	for _, playlist := range playlists {
@@ -198,7 +285,7 @@ func GetPublicPlaylists(c *gin.Context) {
 			"id":         playlist.ID,
 			"title":      playlist.Title,
 			"visibility": playlist.Visibility,
-			"coverurl":   playlist.CoverURL,
+			"coverurl":   utils.FileURL(playlist.DisplayCoverURL()),
 			"owner_name": owner.Name,
 			"song_count": audioCount,
 		}
@@ -206,49 +293,65 @@ func GetPublicPlaylists(c *gin.Context) {
 		response = append(response, playlistDetails)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"playlists": response})
+	c.JSON(http.StatusOK, pagination.Envelope(response, next))
 }
 
 /*
-* GetAudiosByPlaylist fetches all audio tracks associated with a specific playlist.
-* It uses a path parameter 'playlistId' to identify the playlist.
-* No authentication required to view public playlists.
+* GetAudiosByPlaylist fetches a page of the audio tracks of a playlist, in track order.
+* It uses a path parameter 'playlistId' to identify the playlist, and supports 'limit' and 'cursor' query parameters.
+* No authentication required to view public playlists, or unlisted ones with their share token.
  */
 func GetAudiosByPlaylist(c *gin.Context) {
-	playlistId := c.Param("playlistId")
-
-	var playlist models.Playlist
+	playlist, ok := findViewablePlaylist(c, c.Param("playlistId"))
+	if !ok {
+		return
+	}
 
-	if err := initializers.DB.Preload("Audios").Where("id = ?", playlistId).First(&playlist).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
 		return
 	}
 
-	audioList := make([]map[string]interface{}, len(playlist.Audios))
-	for i, audio := range playlist.Audios {
-		owner := models.User{}
-		initializers.DB.First(&owner, audio.Owner)
+	trackPage, next, err := playlistTrackPage(initializers.DB, playlist, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlist tracks"})
+		return
+	}
 
-		audioList[i] = map[string]interface{}{
-			"id":       audio.ID,
-			"title":    audio.Title,
-			"about":    audio.About,
-			"category": audio.Category,
-			"file":     audio.AudioURL,
-			"poster":   audio.CoverURL,
-			"owner": map[string]interface{}{
-				"name": owner.Name,
-				"id":   owner.ID,
-			},
+	// Unreleased tracks of other users are left out of the page, the cursor still moves past them.
+	audios := make([]models.Audio, 0, len(trackPage))
+	tracks := make([]gin.H, 0, len(trackPage))
+	for _, track := range trackPage {
+		if !audioListedFor(&track.Audio, viewerID(c)) {
+			continue
 		}
+		audios = append(audios, track.Audio)
+		tracks = append(tracks, gin.H{
+			"audio_id": track.Audio.ID,
+			"position": track.Position,
+			"added_by": track.AddedBy,
+			"added_at": track.AddedAt,
+		})
+	}
+
+	audioList, err := serializers.Audios(initializers.DB, audios, viewerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load audio details"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"playlist": gin.H{
-			"id":    playlist.ID,
-			"title": playlist.Title,
+			"id":      playlist.ID,
+			"title":   playlist.Title,
+			"version": playlist.Version,
+			"role":    playlistRole(playlist, viewerID(c)),
+			"smart":   playlist.Visibility == models.PlaylistAuto,
 		},
-		"audios": audioList,
+		"audios":      audioList,
+		"tracks":      tracks,
+		"next_cursor": next,
 	})
 }
 
@@ -281,22 +384,53 @@ func UpdatePlaylist(c *gin.Context) {
 		return
 	}
 
-	result := initializers.DB.Model(&models.Playlist{}).
-		Where("id = ? AND owner_id = ?", payload.ID, userModel.ID).
-		Updates(models.Playlist{Title: payload.Title, Visibility: payload.Visibility})
+	if payload.Visibility != "" && !isPlaylistVisibility(payload.Visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be public, private, unlisted or auto"})
+		return
+	}
+
+	var playlist models.Playlist
+	if err := initializers.DB.Where("id = ? AND owner_id = ?", payload.ID, userModel.ID).First(&playlist).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found or not owned by user"})
+		return
+	}
+
+	if payload.Visibility == models.PlaylistAuto && playlist.Rules == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set the rules of a smart playlist to make it auto"})
+		return
+	}
 
-	if result.Error != nil {
+	var shareToken *string
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&playlist).Updates(models.Playlist{Title: payload.Title, Visibility: payload.Visibility}).Error; err != nil {
+			return err
+		}
+		if payload.Visibility != "" {
+			playlist.Visibility = payload.Visibility
+		}
+		if err := recordPlaylistPublished(tx, &playlist, userModel.ID); err != nil {
+			return err
+		}
+
+		var err error
+		shareToken, err = ensureShareToken(tx, &playlist)
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Updating playlist failed"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Playlist updated successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Playlist updated successfully",
+		"share_token": shareToken,
+	})
 }
 
 /*
 * RemoveFromPlaylist removes a single audio track from a specified playlist.
 * It expects form data with 'audioId' and 'playlistId'.
-* Requires user authentication and ownership of the playlist.
+* Requires user authentication and ownership of, or editor access to, the playlist.
  */
 func RemoveFromPlaylist(c *gin.Context) {
 	user, exists := c.Get("user")
@@ -319,23 +453,33 @@ func RemoveFromPlaylist(c *gin.Context) {
 		return
 	}
 
-	var playlist models.Playlist
-	if err := initializers.DB.Where("id = ? AND owner_id = ?", playlistID, userModel.ID).First(&playlist).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found or not owned by user"})
+	playlist, ok := findEditablePlaylist(c, playlistID, userModel)
+	if !ok {
 		return
 	}
 
 	var audio models.Audio
-	if err := initializers.DB.First(&audio, audioID).Error; err == nil {
-		if err := initializers.DB.Model(&playlist).Association("Audios").Delete(&audio); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove audio from playlist"})
-			return
-		}
-	} else {
+	if err := initializers.DB.First(&audio, audioID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Audio not found"})
 		return
 	}
 
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := bumpPlaylistVersion(tx, playlist, nil); err != nil {
+			return err
+		}
+		if _, err := deletePlaylistTracks(tx, playlist.ID, []uint{audio.ID}); err != nil {
+			return err
+		}
+		return logPlaylistActivity(tx, playlist.ID, userModel.ID, "remove_tracks", gin.H{"audioIds": []uint{audio.ID}})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove audio from playlist"})
+		return
+	}
+
+	refreshPlaylistCoverAsync(playlist.ID)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Audio removed from playlist successfully"})
 }
 
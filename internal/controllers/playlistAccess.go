@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+/*
+* canViewPlaylist is the read policy for playlists. Public playlists are readable by everyone, owners and accepted
+* collaborators can always read, and anyone else needs an active share link. Unlisted playlists have a standing
+* link for that, see ensureShareToken.
+ */
+func canViewPlaylist(playlist *models.Playlist, viewer uint, share shareCredentials) bool {
+	if playlist.Visibility == models.PlaylistPublic || playlistRole(playlist, viewer) != "" {
+		return true
+	}
+	return shareLinkGrants(playlist, share)
+}
+
+/*
+* findViewablePlaylist loads a playlist the caller may read.
+* Missing and inaccessible playlists both get a 404 so their existence is not revealed.
+ */
+func findViewablePlaylist(c *gin.Context, playlistID interface{}) (*models.Playlist, bool) {
+	var playlist models.Playlist
+	if err := initializers.DB.Where("id = ?", playlistID).First(&playlist).Error; err != nil ||
+		!canViewPlaylist(&playlist, viewerID(c), requestShare(c)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+		return nil, false
+	}
+	return &playlist, true
+}
+
+func isPlaylistVisibility(visibility string) bool {
+	for _, v := range models.PlaylistVisibilities {
+		if v == visibility {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+* unlistedShareToken returns the token of the standing share link of a playlist, or nil when it has none
+ */
+func unlistedShareToken(db *gorm.DB, playlistID uint) (*string, error) {
+	var links []models.ShareLink
+	if err := db.Where("resource_type = ? AND resource_id = ? AND unlisted AND revoked_at IS NULL", models.ShareResourcePlaylist, playlistID).
+		Order("id DESC").Limit(1).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, nil
+	}
+	return &links[0].Token, nil
+}
+
+/*
+* ensureShareToken gives unlisted playlists a standing share link if they do not have one yet and returns its token.
+* Other playlists get nil.
+ */
+func ensureShareToken(tx *gorm.DB, playlist *models.Playlist) (*string, error) {
+	if playlist.Visibility != models.PlaylistUnlisted {
+		return nil, nil
+	}
+
+	token, err := unlistedShareToken(tx, playlist.ID)
+	if err != nil || token != nil {
+		return token, err
+	}
+
+	link := models.ShareLink{
+		Token:        utils.GenerateRandomHexString(24),
+		ResourceType: models.ShareResourcePlaylist,
+		ResourceID:   playlist.ID,
+		OwnerID:      playlist.Owner,
+		Unlisted:     true,
+	}
+	if err := tx.Create(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link.Token, nil
+}
+
+/*
+* RotatePlaylistShareToken replaces the standing share link of an unlisted playlist, invalidating previously shared URLs.
+* Requires user authentication and ownership of the playlist.
+ */
+func RotatePlaylistShareToken(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var playlist models.Playlist
+	if err := initializers.DB.Where("id = ? AND owner_id = ?", c.Param("playlistId"), userModel.ID).First(&playlist).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found or not owned by user"})
+		return
+	}
+
+	if playlist.Visibility != models.PlaylistUnlisted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only unlisted playlists have a share token"})
+		return
+	}
+
+	var token *string
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ShareLink{}).
+			Where("resource_type = ? AND resource_id = ? AND unlisted AND revoked_at IS NULL", models.ShareResourcePlaylist, playlist.ID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		var err error
+		token, err = ensureShareToken(tx, &playlist)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate share token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Share token rotated successfully",
+		"share_token": *token,
+	})
+}
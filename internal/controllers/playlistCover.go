@@ -0,0 +1,354 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/media"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	mosaicTiles      = 4
+	maxCoverDownload = 10 * middleware.Megabyte
+)
+
+var coverClient = &http.Client{Timeout: 15 * time.Second}
+
+// coverJobs tracks playlists whose mosaic is being generated in this process.
+// The value is true when another refresh was requested while the job ran.
+var (
+	coverJobsMu sync.Mutex
+	coverJobs   = map[uint]bool{}
+)
+
+/*
+* mosaicSources returns the first track covers of a playlist together with a signature of them.
+* The signature changes only when one of those covers does, so unrelated edits keep the cover.
+ */
+func mosaicSources(audios []models.Audio) ([]models.Audio, string) {
+	sources := make([]models.Audio, 0, mosaicTiles)
+	hash := sha1.New()
+	for _, audio := range audios {
+		if audio.CoverURL == "" {
+			continue
+		}
+		sources = append(sources, audio)
+		fmt.Fprintf(hash, "%d:%s:%s\n", audio.ID, audio.CoverPublicID, audio.CoverURL)
+		if len(sources) == mosaicTiles {
+			break
+		}
+	}
+	if len(sources) == 0 {
+		return sources, ""
+	}
+	return sources, hex.EncodeToString(hash.Sum(nil))
+}
+
+/*
+* mosaicCandidates returns the tracks the mosaic of a playlist is drawn from, in order.
+* Smart playlists use their newest matches, so random or play count sorts do not change the cover on every read.
+ */
+func mosaicCandidates(playlist *models.Playlist, audios []models.Audio) []models.Audio {
+	if playlist.Visibility != models.PlaylistAuto {
+		return audios
+	}
+
+	newest := append([]models.Audio(nil), audios...)
+	sort.SliceStable(newest, func(i, j int) bool {
+		if !newest[i].CreatedAt.Equal(newest[j].CreatedAt) {
+			return newest[i].CreatedAt.After(newest[j].CreatedAt)
+		}
+		return newest[i].ID > newest[j].ID
+	})
+	return newest
+}
+
+/*
+* mosaicOutdated reports whether the generated cover of a playlist no longer matches its tracks
+ */
+func mosaicOutdated(playlist *models.Playlist, audios []models.Audio) bool {
+	if playlist.CustomCover {
+		return false
+	}
+
+	_, signature := mosaicSources(mosaicCandidates(playlist, audios))
+	if signature != playlist.CoverSignature {
+		return true
+	}
+	if signature == "" {
+		return playlist.CoverURL != ""
+	}
+	return playlist.CoverPublicID == ""
+}
+
+/*
+* refreshPlaylistCoverAsync regenerates the mosaic cover of a playlist in the background.
+* Requests made while a refresh is running are coalesced into one more run.
+ */
+func refreshPlaylistCoverAsync(playlistID uint) {
+	coverJobsMu.Lock()
+	if _, running := coverJobs[playlistID]; running {
+		coverJobs[playlistID] = true
+		coverJobsMu.Unlock()
+		return
+	}
+	coverJobs[playlistID] = false
+	coverJobsMu.Unlock()
+
+	go func() {
+		for {
+			if err := refreshPlaylistCover(playlistID); err != nil {
+				log.Printf("Failed to generate cover of playlist %d: %v", playlistID, err)
+			}
+
+			coverJobsMu.Lock()
+			if !coverJobs[playlistID] {
+				delete(coverJobs, playlistID)
+				coverJobsMu.Unlock()
+				return
+			}
+			coverJobs[playlistID] = false
+			coverJobsMu.Unlock()
+		}
+	}()
+}
+
+/*
+* refreshCoversWithAudio regenerates the covers of playlists that show the given audio in their mosaic
+ */
+func refreshCoversWithAudio(audioID uint) {
+	var playlistIDs []uint
+	if err := initializers.DB.Model(&models.PlaylistAudio{}).
+		Where("audio_id = ? AND position < ?", audioID, mosaicTiles).
+		Pluck("playlist_id", &playlistIDs).Error; err != nil {
+		log.Printf("Failed to find playlists of audio %d: %v", audioID, err)
+		return
+	}
+	for _, playlistID := range playlistIDs {
+		refreshPlaylistCoverAsync(playlistID)
+	}
+}
+
+/*
+* refreshPlaylistCover builds the mosaic of the first track covers and stores it, unless the owner set a custom cover
+* or the covers did not change since the last run.
+ */
+func refreshPlaylistCover(playlistID uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	var playlist models.Playlist
+	if err := initializers.DB.First(&playlist, playlistID).Error; err != nil {
+		return err
+	}
+	audios, err := playlistTrackAudios(initializers.DB, &playlist)
+	if err != nil {
+		return err
+	}
+	// Every reader sees the mosaic, so unreleased tracks stay out of it.
+	audios = filterVisibleAudios(audios, 0)
+
+	if !mosaicOutdated(&playlist, audios) {
+		return nil
+	}
+	sources, signature := mosaicSources(mosaicCandidates(&playlist, audios))
+
+	updates := map[string]interface{}{
+		"cover_url":       "",
+		"cover_public_id": "",
+		"cover_signature": signature,
+	}
+
+	if len(sources) > 0 {
+		images := make([]image.Image, 0, len(sources))
+		for _, audio := range sources {
+			img, err := loadAudioCover(ctx, audio)
+			if err != nil {
+				log.Printf("Skipping cover of audio %d in playlist %d: %v", audio.ID, playlistID, err)
+				continue
+			}
+			images = append(images, img)
+		}
+
+		if len(images) > 0 {
+			var body bytes.Buffer
+			if err := jpeg.Encode(&body, media.Mosaic(images, media.MosaicSize), &jpeg.Options{Quality: 85}); err != nil {
+				return err
+			}
+
+			info, err := utils.UploadReader(ctx, &body, int64(body.Len()), "playlist-covers", "mosaic.jpg", "image/jpeg")
+			if err != nil {
+				return err
+			}
+			updates["cover_url"], updates["cover_public_id"] = info.URL, info.Key
+		}
+	}
+
+	// Skip the write if the owner uploaded a cover in the meantime.
+	result := initializers.DB.Model(&models.Playlist{}).
+		Where("id = ? AND custom_cover = ?", playlist.ID, false).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	stale := playlist.CoverPublicID
+	if result.RowsAffected == 0 {
+		stale, _ = updates["cover_public_id"].(string)
+	}
+	if stale != "" {
+		if err := utils.DeleteFile(ctx, stale); err != nil {
+			log.Printf("Failed to delete previous cover of playlist %d: %v", playlistID, err)
+		}
+	}
+	return nil
+}
+
+/*
+* loadAudioCover reads the cover of an audio from the storage backend, or downloads it when it only exists as a URL
+ */
+func loadAudioCover(ctx context.Context, audio models.Audio) (image.Image, error) {
+	if audio.CoverPublicID != "" {
+		if object, err := initializers.Storage.Open(ctx, audio.CoverPublicID); err == nil {
+			defer object.Close()
+			return media.DecodeImage(io.LimitReader(object, maxCoverDownload))
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, audio.CoverURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := coverClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cover download returned %s", resp.Status)
+	}
+	return media.DecodeImage(io.LimitReader(resp.Body, maxCoverDownload))
+}
+
+/*
+* UploadPlaylistCover replaces the generated cover of a playlist with an uploaded image.
+* It expects form data with a 'coverFile' field.
+* Requires user authentication and ownership of the playlist.
+ */
+func UploadPlaylistCover(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	coverFile, err := c.FormFile("coverFile")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required fields"})
+		return
+	}
+
+	var playlist models.Playlist
+	if err := initializers.DB.Where("id = ? AND owner_id = ?", c.Param("playlistId"), userModel.ID).First(&playlist).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found or not owned by user"})
+		return
+	}
+
+	coverInfo, err := utils.UploadFile(c.Request.Context(), coverFile, "playlist-covers")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload cover file"})
+		return
+	}
+
+	if err := initializers.DB.Model(&playlist).Updates(map[string]interface{}{
+		"cover_url":       coverInfo.URL,
+		"cover_public_id": coverInfo.Key,
+		"custom_cover":    true,
+		"cover_signature": "",
+	}).Error; err != nil {
+		utils.DeleteFile(c.Request.Context(), coverInfo.Key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update playlist cover"})
+		return
+	}
+
+	if playlist.CoverPublicID != "" {
+		if err := utils.DeleteFile(c.Request.Context(), playlist.CoverPublicID); err != nil {
+			log.Printf("Failed to delete previous cover of playlist %d: %v", playlist.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Playlist cover updated successfully",
+		"coverurl": utils.FileURL(coverInfo.URL),
+	})
+}
+
+/*
+* DeletePlaylistCover removes the uploaded cover of a playlist and goes back to the generated mosaic.
+* Requires user authentication and ownership of the playlist.
+ */
+func DeletePlaylistCover(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var playlist models.Playlist
+	if err := initializers.DB.Where("id = ? AND owner_id = ?", c.Param("playlistId"), userModel.ID).First(&playlist).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found or not owned by user"})
+		return
+	}
+
+	if !playlist.CustomCover {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Playlist does not have a custom cover"})
+		return
+	}
+
+	if err := initializers.DB.Model(&playlist).Updates(map[string]interface{}{
+		"cover_url":       "",
+		"cover_public_id": "",
+		"custom_cover":    false,
+		"cover_signature": "",
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update playlist cover"})
+		return
+	}
+
+	if err := utils.DeleteFile(c.Request.Context(), playlist.CoverPublicID); err != nil {
+		log.Printf("Failed to delete custom cover of playlist %d: %v", playlist.ID, err)
+	}
+
+	refreshPlaylistCoverAsync(playlist.ID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Playlist cover reset successfully"})
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestMosaicSignatureOfSmartPlaylists(t *testing.T) {
+	now := time.Now()
+	audio := func(id uint, age time.Duration) models.Audio {
+		a := models.Audio{CoverURL: "https://example.com/cover.jpg"}
+		a.ID = id
+		a.CreatedAt = now.Add(-age)
+		return a
+	}
+	tracks := []models.Audio{audio(1, 5*time.Hour), audio(2, time.Hour), audio(3, 3*time.Hour), audio(4, 4*time.Hour), audio(5, 2*time.Hour), audio(6, 6*time.Hour)}
+	shuffled := []models.Audio{tracks[5], tracks[3], tracks[1], tracks[0], tracks[4], tracks[2]}
+
+	tests := []struct {
+		name       string
+		visibility string
+		same       bool
+	}{
+		{"smart playlists ignore the track order", models.PlaylistAuto, true},
+		{"manual playlists follow the track order", models.PlaylistPublic, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			playlist := &models.Playlist{Visibility: tt.visibility}
+			_, first := mosaicSources(mosaicCandidates(playlist, tracks))
+			_, second := mosaicSources(mosaicCandidates(playlist, shuffled))
+			if (first == second) != tt.same {
+				t.Errorf("signatures %q and %q, want equal %v", first, second, tt.same)
+			}
+		})
+	}
+
+	sources, _ := mosaicSources(mosaicCandidates(&models.Playlist{Visibility: models.PlaylistAuto}, shuffled))
+	for i, want := range []uint{2, 5, 3, 4} {
+		if sources[i].ID != want {
+			t.Errorf("sources[%d] = audio %d, want %d", i, sources[i].ID, want)
+		}
+	}
+}
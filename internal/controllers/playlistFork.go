@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+/*
+* forkedFrom describes the source of a forked playlist for the given viewer.
+* Sources that were deleted or are no longer visible to the viewer are reported without details.
+ */
+func forkedFrom(playlist *models.Playlist, viewer uint) gin.H {
+	if playlist.ForkedFromID == nil {
+		return nil
+	}
+
+	var source models.Playlist
+	if err := initializers.DB.First(&source, *playlist.ForkedFromID).Error; err != nil ||
+		!canViewPlaylist(&source, viewer, shareCredentials{}) {
+		return gin.H{"available": false}
+	}
+
+	var owner models.User
+	initializers.DB.First(&owner, source.Owner)
+
+	return gin.H{
+		"available":  true,
+		"id":         source.ID,
+		"title":      source.Title,
+		"owner_id":   owner.ID,
+		"owner_name": owner.Name,
+	}
+}
+
+/*
+* ForkPlaylist copies the ordered tracks of a playlist into a new private playlist owned by the user.
+* It uses a path parameter 'playlistId' and accepts an optional 'title' form field.
+* Smart playlists are copied as a snapshot of their current tracks.
+* Requires user authentication. Only public playlists and playlists the user owns or collaborates on can be forked;
+* share tokens and share links grant read access only.
+ */
+func ForkPlaylist(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var source models.Playlist
+	if err := initializers.DB.Where("id = ?", c.Param("playlistId")).First(&source).Error; err != nil ||
+		(source.Visibility != models.PlaylistPublic && playlistRole(&source, userModel.ID) == "") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+		return
+	}
+
+	audios, err := playlistTrackAudios(initializers.DB, &source)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlist tracks"})
+		return
+	}
+	audios = filterVisibleAudios(audios, userModel.ID)
+
+	title := strings.TrimSpace(c.PostForm("title"))
+	if title == "" {
+		title = source.Title
+	}
+
+	fork := models.Playlist{
+		Title:        title,
+		Owner:        userModel.ID,
+		Visibility:   models.PlaylistPrivate,
+		ForkedFromID: &source.ID,
+	}
+
+	audioIDs := make([]uint, len(audios))
+	for i, audio := range audios {
+		audioIDs[i] = audio.ID
+	}
+
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&fork).Error; err != nil {
+			return err
+		}
+		if len(audioIDs) > 0 {
+			if _, err := insertPlaylistTracks(tx, fork.ID, audioIDs, nil, userModel.ID); err != nil {
+				return err
+			}
+		}
+		return logPlaylistActivity(tx, fork.ID, userModel.ID, "fork", gin.H{"sourceId": source.ID})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fork playlist"})
+		return
+	}
+
+	refreshPlaylistCoverAsync(fork.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Playlist forked successfully",
+		"playlist": gin.H{
+			"id":          fork.ID,
+			"title":       fork.Title,
+			"visibility":  fork.Visibility,
+			"forked_from": source.ID,
+			"song_count":  len(audioIDs),
+		},
+	})
+}
@@ -0,0 +1,522 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/models"
+	"backend/internal/pagination"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errPlaylistVersionConflict = errors.New("playlist version conflict")
+
+/*
+* findEditablePlaylist loads a playlist the authenticated user may change the tracks of.
+* Owners and accepted editors qualify. Smart playlists are rejected since their tracks follow their rules.
+ */
+func findEditablePlaylist(c *gin.Context, playlistID interface{}, userModel *models.User) (*models.Playlist, bool) {
+	var playlist models.Playlist
+	if err := initializers.DB.Where("id = ?", playlistID).First(&playlist).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+		return nil, false
+	}
+
+	switch playlistRole(&playlist, userModel.ID) {
+	case models.PlaylistRoleOwner, models.PlaylistRoleEditor:
+		if playlist.Visibility == models.PlaylistAuto {
+			c.JSON(http.StatusConflict, gin.H{"error": "Tracks of a smart playlist are defined by its rules"})
+			return nil, false
+		}
+		return &playlist, true
+	case models.PlaylistRoleViewer:
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to edit this playlist"})
+		return nil, false
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+		return nil, false
+	}
+}
+
+/*
+* playlistTrackAudios returns the audios of a playlist in track order.
+* Tracks of smart playlists are computed from their rules on every read.
+ */
+func playlistTrackAudios(db *gorm.DB, playlist *models.Playlist) ([]models.Audio, error) {
+	if playlist.Visibility == models.PlaylistAuto {
+		return smartPlaylistAudios(db, playlist)
+	}
+
+	var audios []models.Audio
+	err := db.
+		Joins("JOIN playlist_audios ON playlist_audios.audio_id = audios.id").
+		Where("playlist_audios.playlist_id = ?", playlist.ID).
+		Order("playlist_audios.position, playlist_audios.created_at, playlist_audios.audio_id").
+		Find(&audios).Error
+	return audios, err
+}
+
+// playlistTrack is an audio together with its place in a playlist.
+type playlistTrack struct {
+	Audio    models.Audio
+	Position int
+	AddedBy  uint
+	AddedAt  time.Time
+}
+
+/*
+* playlistTrackPage returns one page of the tracks of a playlist in track order and the cursor of the next page.
+* Tracks are paged by (position, created_at, audio_id), the computed tracks of smart playlists by index.
+ */
+func playlistTrackPage(db *gorm.DB, playlist *models.Playlist, page pagination.Page) ([]playlistTrack, *string, error) {
+	if playlist.Visibility == models.PlaylistAuto {
+		audios, err := smartPlaylistAudios(db, playlist)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		start := 0
+		if page.After != nil {
+			start = page.After.Position + 1
+		}
+		if start > len(audios) {
+			start = len(audios)
+		}
+
+		tracks := make([]playlistTrack, 0, page.Limit+1)
+		for i := start; i < len(audios) && len(tracks) <= page.Limit; i++ {
+			tracks = append(tracks, playlistTrack{Audio: audios[i], Position: i})
+		}
+		tracks, next := pagination.Trim(page, tracks, func(track playlistTrack) pagination.Cursor {
+			return pagination.Cursor{ID: track.Audio.ID, Position: track.Position}
+		})
+		return tracks, next, nil
+	}
+
+	var entries []models.PlaylistAudio
+	if err := db.Scopes(page.PositionScope("position", "created_at", "audio_id")).
+		Where("playlist_id = ?", playlist.ID).
+		Find(&entries).Error; err != nil {
+		return nil, nil, err
+	}
+
+	// The cursor comes from the entries, so tracks of deleted audios are skipped without ending the list early.
+	entries, next := pagination.Trim(page, entries, func(entry models.PlaylistAudio) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: entry.CreatedAt, ID: entry.AudioID, Position: entry.Position}
+	})
+
+	audioIDs := make([]uint, len(entries))
+	for i, entry := range entries {
+		audioIDs[i] = entry.AudioID
+	}
+
+	var audios []models.Audio
+	if err := db.Where("id IN ?", audioIDs).Find(&audios).Error; err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[uint]models.Audio, len(audios))
+	for _, audio := range audios {
+		byID[audio.ID] = audio
+	}
+
+	tracks := make([]playlistTrack, 0, len(entries))
+	for _, entry := range entries {
+		audio, ok := byID[entry.AudioID]
+		if !ok {
+			continue
+		}
+		tracks = append(tracks, playlistTrack{
+			Audio:    audio,
+			Position: entry.Position,
+			AddedBy:  entry.AddedBy,
+			AddedAt:  entry.CreatedAt,
+		})
+	}
+	return tracks, next, nil
+}
+
+/*
+* bumpPlaylistVersion increments the version of a playlist, locking its row until the transaction ends.
+* When expected is set it must match the stored version, otherwise errPlaylistVersionConflict is returned.
+ */
+func bumpPlaylistVersion(tx *gorm.DB, playlist *models.Playlist, expected *uint) error {
+	query := tx.Model(&models.Playlist{}).Where("id = ?", playlist.ID)
+	if expected != nil {
+		query = query.Where("version = ?", *expected)
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"version":    gorm.Expr("version + 1"),
+		"updated_at": time.Now(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errPlaylistVersionConflict
+	}
+
+	return tx.Select("version").First(playlist, playlist.ID).Error
+}
+
+/*
+* playlistOrder returns the audio IDs of a playlist in track order
+ */
+func playlistOrder(tx *gorm.DB, playlistID uint) ([]uint, error) {
+	var audioIDs []uint
+	err := tx.Model(&models.PlaylistAudio{}).
+		Where("playlist_id = ?", playlistID).
+		Order("position, created_at, audio_id").
+		Pluck("audio_id", &audioIDs).Error
+	return audioIDs, err
+}
+
+/*
+* writePlaylistOrder stores the given order, assigning positions 0..n-1 and only touching rows that moved
+ */
+func writePlaylistOrder(tx *gorm.DB, playlistID uint, audioIDs []uint) error {
+	var rows []models.PlaylistAudio
+	if err := tx.Where("playlist_id = ?", playlistID).Find(&rows).Error; err != nil {
+		return err
+	}
+
+	current := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		current[row.AudioID] = row.Position
+	}
+
+	for position, audioID := range audioIDs {
+		if old, ok := current[audioID]; ok && old == position {
+			continue
+		}
+		if err := tx.Model(&models.PlaylistAudio{}).
+			Where("playlist_id = ? AND audio_id = ?", playlistID, audioID).
+			UpdateColumn("position", position).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+* insertPlaylistTracks adds audios to a playlist at the given index, or at the end when position is nil.
+* Audios already in the playlist are skipped and returned.
+ */
+func insertPlaylistTracks(tx *gorm.DB, playlistID uint, audioIDs []uint, position *int, addedBy uint) ([]uint, error) {
+	order, err := playlistOrder(tx, playlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	present := make(map[uint]bool, len(order))
+	for _, id := range order {
+		present[id] = true
+	}
+
+	var added, skipped []uint
+	for _, id := range audioIDs {
+		if present[id] {
+			skipped = append(skipped, id)
+			continue
+		}
+		present[id] = true
+		added = append(added, id)
+	}
+
+	if len(added) == 0 {
+		return skipped, nil
+	}
+
+	index := len(order)
+	if position != nil && *position < index {
+		index = *position
+	}
+
+	rows := make([]models.PlaylistAudio, len(added))
+	for i, id := range added {
+		rows[i] = models.PlaylistAudio{PlaylistID: playlistID, AudioID: id, Position: index + i, AddedBy: addedBy}
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	newOrder := make([]uint, 0, len(order)+len(added))
+	newOrder = append(newOrder, order[:index]...)
+	newOrder = append(newOrder, added...)
+	newOrder = append(newOrder, order[index:]...)
+
+	return skipped, writePlaylistOrder(tx, playlistID, newOrder)
+}
+
+/*
+* deletePlaylistTracks removes audios from a playlist and closes the gaps they leave
+ */
+func deletePlaylistTracks(tx *gorm.DB, playlistID uint, audioIDs []uint) (int64, error) {
+	result := tx.Where("playlist_id = ? AND audio_id IN ?", playlistID, audioIDs).Delete(&models.PlaylistAudio{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	order, err := playlistOrder(tx, playlistID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected, writePlaylistOrder(tx, playlistID, order)
+}
+
+func respondPlaylistWriteError(c *gin.Context, err error, playlistID uint) {
+	if errors.Is(err, errPlaylistVersionConflict) {
+		var current models.Playlist
+		initializers.DB.Select("version").First(&current, playlistID)
+		c.JSON(http.StatusConflict, gin.H{"error": "Playlist was changed by someone else, reload and try again", "version": current.Version})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update playlist"})
+}
+
+/*
+* AddPlaylistTracks adds several audios to a playlist.
+* It expects a JSON payload with 'audioIds', and optionally 'position' (defaults to the end) and 'version'.
+ */
+func AddPlaylistTracks(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var req models.AddPlaylistTracks
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if validationErr := Validate.Struct(req); validationErr != nil {
+		c.Error(validationErr)
+		return
+	}
+
+	playlist, ok := findEditablePlaylist(c, c.Param("playlistId"), userModel)
+	if !ok {
+		return
+	}
+
+	var found int64
+	if err := initializers.DB.Model(&models.Audio{}).Scopes(visibleAudios(userModel.ID)).Where("id IN ?", req.AudioIDs).Count(&found).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query audios"})
+		return
+	}
+	if found != int64(len(uniqueIDs(req.AudioIDs))) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "One or more audios not found"})
+		return
+	}
+
+	var skipped []uint
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := bumpPlaylistVersion(tx, playlist, req.Version); err != nil {
+			return err
+		}
+
+		var err error
+		skipped, err = insertPlaylistTracks(tx, playlist.ID, req.AudioIDs, req.Position, userModel.ID)
+		if err != nil {
+			return err
+		}
+		if err := recordTracksAdded(tx, playlist, userModel.ID, addedIDs(req.AudioIDs, skipped)); err != nil {
+			return err
+		}
+		return logPlaylistActivity(tx, playlist.ID, userModel.ID, "add_tracks", gin.H{"audioIds": req.AudioIDs, "skipped": skipped})
+	})
+	if err != nil {
+		respondPlaylistWriteError(c, err, playlist.ID)
+		return
+	}
+
+	refreshPlaylistCoverAsync(playlist.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Audios added to playlist successfully",
+		"skipped": skipped,
+		"version": playlist.Version,
+	})
+}
+
+/*
+* RemovePlaylistTracks removes several audios from a playlist.
+* It expects a JSON payload with 'audioIds' and optionally 'version'.
+ */
+func RemovePlaylistTracks(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var req models.RemovePlaylistTracks
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if validationErr := Validate.Struct(req); validationErr != nil {
+		c.Error(validationErr)
+		return
+	}
+
+	playlist, ok := findEditablePlaylist(c, c.Param("playlistId"), userModel)
+	if !ok {
+		return
+	}
+
+	var removed int64
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := bumpPlaylistVersion(tx, playlist, req.Version); err != nil {
+			return err
+		}
+
+		var err error
+		removed, err = deletePlaylistTracks(tx, playlist.ID, req.AudioIDs)
+		if err != nil {
+			return err
+		}
+		return logPlaylistActivity(tx, playlist.ID, userModel.ID, "remove_tracks", gin.H{"audioIds": req.AudioIDs})
+	})
+	if err != nil {
+		respondPlaylistWriteError(c, err, playlist.ID)
+		return
+	}
+
+	refreshPlaylistCoverAsync(playlist.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Audios removed from playlist successfully",
+		"removed": removed,
+		"version": playlist.Version,
+	})
+}
+
+/*
+* MovePlaylistTrack moves a track of a playlist to a new index.
+* It expects a JSON payload with 'position' and the 'version' of the playlist the client last saw.
+ */
+func MovePlaylistTrack(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	audioID, err := strconv.ParseUint(c.Param("audioId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid audio ID"})
+		return
+	}
+
+	var req models.MovePlaylistTrack
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if validationErr := Validate.Struct(req); validationErr != nil {
+		c.Error(validationErr)
+		return
+	}
+
+	playlist, ok := findEditablePlaylist(c, c.Param("playlistId"), userModel)
+	if !ok {
+		return
+	}
+
+	errTrackNotFound := errors.New("track not in playlist")
+
+	var order []uint
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := bumpPlaylistVersion(tx, playlist, req.Version); err != nil {
+			return err
+		}
+
+		var err error
+		order, err = playlistOrder(tx, playlist.ID)
+		if err != nil {
+			return err
+		}
+
+		from := -1
+		for i, id := range order {
+			if id == uint(audioID) {
+				from = i
+				break
+			}
+		}
+		if from < 0 {
+			return errTrackNotFound
+		}
+
+		to := *req.Position
+		if to >= len(order) {
+			to = len(order) - 1
+		}
+
+		order = append(order[:from], order[from+1:]...)
+		order = append(order[:to], append([]uint{uint(audioID)}, order[to:]...)...)
+
+		if err := writePlaylistOrder(tx, playlist.ID, order); err != nil {
+			return err
+		}
+		return logPlaylistActivity(tx, playlist.ID, userModel.ID, "move_track", gin.H{"audioId": audioID, "from": from, "to": to})
+	})
+
+	if errors.Is(err, errTrackNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Audio not in playlist"})
+		return
+	}
+	if err != nil {
+		respondPlaylistWriteError(c, err, playlist.ID)
+		return
+	}
+
+	refreshPlaylistCoverAsync(playlist.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Track moved successfully",
+		"order":   order,
+		"version": playlist.Version,
+	})
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,391 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/playlistfile"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	maxImportSize    = 2 * middleware.Megabyte
+	maxImportEntries = 1000
+)
+
+var (
+	streamPathPattern = regexp.MustCompile(`^/audio/(\d+)/stream/?$`)
+	unsafeFilename    = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+/*
+* apiBaseURL is the public URL of this API, used for links in exported files.
+* It comes from API_URL when set, otherwise from the request.
+ */
+func apiBaseURL(c *gin.Context) string {
+	if base := os.Getenv("API_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+func streamURL(base string, audioID uint) string {
+	return fmt.Sprintf("%s/audio/%d/stream", base, audioID)
+}
+
+/*
+* ExportPlaylist downloads a playlist as an M3U8, XSPF or JSON file referencing the stream URLs of its tracks.
+* It uses a path parameter 'playlistId' and the 'format' query parameter (default m3u8).
+* The playlist must be readable by the caller, see canViewPlaylist.
+ */
+func ExportPlaylist(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", playlistfile.FormatM3U8))
+	contentType, ext, err := playlistfile.ContentType(format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of m3u8, xspf or json"})
+		return
+	}
+
+	playlist, ok := findViewablePlaylist(c, c.Param("playlistId"))
+	if !ok {
+		return
+	}
+
+	audios, err := playlistTrackAudios(initializers.DB, playlist)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlist tracks"})
+		return
+	}
+	audios = filterVisibleAudios(audios, viewerID(c))
+
+	base := apiBaseURL(c)
+	file := playlistfile.Playlist{Title: playlist.Title, Entries: make([]playlistfile.Entry, len(audios))}
+	for i, audio := range audios {
+		file.Entries[i] = playlistfile.Entry{
+			Line:       i + 1,
+			ID:         audio.ID,
+			Identifier: streamURL(base, audio.ID),
+			Location:   streamURL(base, audio.ID),
+			Title:      audio.Title,
+			Artist:     audio.Artist,
+			Album:      audio.Album,
+			Duration:   int(audio.Duration),
+		}
+	}
+
+	var body bytes.Buffer
+	if err := playlistfile.Write(&body, format, file); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export playlist"})
+		return
+	}
+
+	filename := strings.Trim(unsafeFilename.ReplaceAllString(playlist.Title, "_"), "_")
+	if filename == "" {
+		filename = "playlist-" + strconv.FormatUint(uint64(playlist.ID), 10)
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s%s"`, filename, ext))
+	c.Data(http.StatusOK, contentType+"; charset=utf-8", body.Bytes())
+}
+
+/*
+* ownAudioID returns the audio a URL points to when it is one of our stream URLs.
+* URLs on other hosts are ignored, so files from elsewhere cannot pick audios by ID.
+ */
+func ownAudioID(base *url.URL, raw string) (uint, bool) {
+	location, err := url.Parse(raw)
+	if err != nil || !strings.EqualFold(location.Host, base.Host) || !strings.HasPrefix(location.Path, base.Path) {
+		return 0, false
+	}
+
+	match := streamPathPattern.FindStringSubmatch(strings.TrimPrefix(location.Path, base.Path))
+	if match == nil {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(match[1], 10, 0)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func titleKey(title, artist string) string {
+	return strings.ToLower(title) + "\x00" + strings.ToLower(artist)
+}
+
+/*
+* importCandidates returns the audio IDs an entry names, in order of preference: its own ID,
+* then one of our stream URLs in the identifier or location.
+ */
+func importCandidates(base *url.URL, entry playlistfile.Entry) []uint {
+	candidates := make([]uint, 0, 2)
+	if entry.ID != 0 {
+		candidates = append(candidates, entry.ID)
+	}
+	for _, raw := range []string{entry.Identifier, entry.Location} {
+		if id, ok := ownAudioID(base, raw); ok {
+			candidates = append(candidates, id)
+			break
+		}
+	}
+	return candidates
+}
+
+/*
+* matchImportEntries finds the audios imported entries refer to, trying in order the entry ID,
+* one of our stream URLs in the identifier or location, the stored audio URL and finally title and artist.
+* Each kind of lookup is done in one query for all entries.
+ */
+func matchImportEntries(db *gorm.DB, base string, entries []playlistfile.Entry) ([]uint, []playlistfile.Entry, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, nil, err
+	}
+	baseURL.Path = strings.TrimRight(baseURL.Path, "/")
+
+	matched := make([]uint, len(entries))
+
+	candidates := make([][]uint, len(entries))
+	ids := make([]uint, 0, len(entries))
+	for i, entry := range entries {
+		candidates[i] = importCandidates(baseURL, entry)
+		ids = append(ids, candidates[i]...)
+	}
+
+	var existing []uint
+	if len(ids) > 0 {
+		if err := db.Model(&models.Audio{}).Where("id IN ?", uniqueIDs(ids)).Pluck("id", &existing).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	found := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		found[id] = true
+	}
+
+	for i := range entries {
+		for _, id := range candidates[i] {
+			if found[id] {
+				matched[i] = id
+				break
+			}
+		}
+	}
+
+	locations := make([]string, 0)
+	for i, entry := range entries {
+		if matched[i] == 0 {
+			if entry.Location != "" {
+				locations = append(locations, entry.Location)
+			}
+		}
+	}
+
+	byLocation := make(map[string]uint)
+	if len(locations) > 0 {
+		var audios []models.Audio
+		if err := db.Select("id", "audio_url").Where("audio_url IN ?", locations).Order("id").Find(&audios).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, audio := range audios {
+			if _, ok := byLocation[audio.AudioURL]; !ok {
+				byLocation[audio.AudioURL] = audio.ID
+			}
+		}
+	}
+
+	titles := make([]string, 0)
+	for i, entry := range entries {
+		if matched[i] == 0 {
+			matched[i] = byLocation[entry.Location]
+		}
+		if matched[i] == 0 && entry.Title != "" {
+			titles = append(titles, strings.ToLower(entry.Title))
+		}
+	}
+
+	byTitle := make(map[string]uint)
+	byTitleAndArtist := make(map[string]uint)
+	if len(titles) > 0 {
+		var audios []models.Audio
+		if err := db.Select("id", "name", "artist").Where("lower(name) IN ?", titles).Order("id").Find(&audios).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, audio := range audios {
+			if _, ok := byTitle[strings.ToLower(audio.Title)]; !ok {
+				byTitle[strings.ToLower(audio.Title)] = audio.ID
+			}
+			if _, ok := byTitleAndArtist[titleKey(audio.Title, audio.Artist)]; !ok {
+				byTitleAndArtist[titleKey(audio.Title, audio.Artist)] = audio.ID
+			}
+		}
+	}
+
+	audioIDs := make([]uint, 0, len(entries))
+	unmatched := make([]playlistfile.Entry, 0)
+	for i, entry := range entries {
+		id := matched[i]
+		if id == 0 && entry.Title != "" {
+			if entry.Artist != "" {
+				id = byTitleAndArtist[titleKey(entry.Title, entry.Artist)]
+			} else {
+				id = byTitle[strings.ToLower(entry.Title)]
+			}
+		}
+
+		if id != 0 {
+			audioIDs = append(audioIDs, id)
+		} else {
+			unmatched = append(unmatched, entry)
+		}
+	}
+	return audioIDs, unmatched, nil
+}
+
+/*
+* ImportPlaylist creates a playlist from an uploaded M3U8, XSPF or JSON file.
+* It expects multipart form data with a 'file' field and optional 'format', 'title' and 'visibility'
+* (public, private or unlisted, default private) fields. Entries are matched to existing audios and the ones
+* that could not be matched are reported with their line.
+* Requires user authentication.
+ */
+func ImportPlaylist(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing playlist file"})
+		return
+	}
+	if header.Size > maxImportSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Playlist file is too large"})
+		return
+	}
+
+	src, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read playlist file"})
+		return
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, maxImportSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read playlist file"})
+		return
+	}
+
+	format := strings.ToLower(c.PostForm("format"))
+	if format == "" {
+		if format, err = playlistfile.DetectFormat(header.Filename, data); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unrecognized playlist format"})
+			return
+		}
+	}
+
+	file, err := playlistfile.Parse(bytes.NewReader(data), format)
+	if err == playlistfile.ErrUnknownFormat {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of m3u8, xspf or json"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Malformed playlist file"})
+		return
+	}
+
+	if len(file.Entries) > maxImportEntries {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Playlist files may contain at most %d tracks", maxImportEntries)})
+		return
+	}
+
+	visibility := c.DefaultPostForm("visibility", models.PlaylistPrivate)
+	if visibility != models.PlaylistPublic && visibility != models.PlaylistPrivate && visibility != models.PlaylistUnlisted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be public, private or unlisted"})
+		return
+	}
+
+	title := strings.TrimSpace(c.PostForm("title"))
+	if title == "" {
+		title = file.Title
+	}
+	if title == "" {
+		title = strings.TrimSuffix(header.Filename, filepath.Ext(header.Filename))
+	}
+
+	audioIDs, unmatched, err := matchImportEntries(initializers.DB, apiBaseURL(c), file.Entries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to match playlist entries"})
+		return
+	}
+	audioIDs = uniqueIDs(audioIDs)
+
+	if len(audioIDs) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":     "None of the playlist entries matched an audio",
+			"unmatched": unmatched,
+		})
+		return
+	}
+
+	playlist := models.Playlist{Title: title, Owner: userModel.ID, Visibility: visibility}
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&playlist).Error; err != nil {
+			return err
+		}
+		if _, err := ensureShareToken(tx, &playlist); err != nil {
+			return err
+		}
+		if _, err := insertPlaylistTracks(tx, playlist.ID, audioIDs, nil, userModel.ID); err != nil {
+			return err
+		}
+		if err := recordPlaylistPublished(tx, &playlist, userModel.ID); err != nil {
+			return err
+		}
+		return logPlaylistActivity(tx, playlist.ID, userModel.ID, "import", gin.H{"format": format, "audioIds": audioIDs})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import playlist"})
+		return
+	}
+
+	refreshPlaylistCoverAsync(playlist.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Playlist imported successfully",
+		"playlist": gin.H{
+			"id":         playlist.ID,
+			"title":      playlist.Title,
+			"visibility": playlist.Visibility,
+		},
+		"matched":   len(audioIDs),
+		"unmatched": unmatched,
+	})
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"backend/internal/playlistfile"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestOwnAudioID(t *testing.T) {
+	tests := []struct {
+		name string
+		base string
+		raw  string
+		id   uint
+		ok   bool
+	}{
+		{"stream url", "https://api.example.com", "https://api.example.com/audio/42/stream", 42, true},
+		{"trailing slash", "https://api.example.com", "https://api.example.com/audio/42/stream/", 42, true},
+		{"host is case insensitive", "https://api.example.com", "http://API.example.com/audio/42/stream", 42, true},
+		{"base path", "https://example.com/api", "https://example.com/api/audio/7/stream", 7, true},
+		{"outside base path", "https://example.com/api", "https://example.com/audio/7/stream", 0, false},
+		{"other host", "https://api.example.com", "https://evil.example.net/audio/42/stream", 0, false},
+		{"nested path on other host", "https://api.example.com", "https://evil.example.net/x/audio/42/stream", 0, false},
+		{"not a stream url", "https://api.example.com", "https://api.example.com/audio/42", 0, false},
+		{"bare id", "https://api.example.com", "42", 0, false},
+		{"zero id", "https://api.example.com", "https://api.example.com/audio/0/stream", 0, false},
+		{"empty", "https://api.example.com", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, err := url.Parse(tt.base)
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, ok := ownAudioID(base, tt.raw)
+			if id != tt.id || ok != tt.ok {
+				t.Errorf("ownAudioID(%q) = %d, %v, want %d, %v", tt.raw, id, ok, tt.id, tt.ok)
+			}
+		})
+	}
+}
+
+func TestImportCandidates(t *testing.T) {
+	base, err := url.Parse("https://api.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		entry playlistfile.Entry
+		want  []uint
+	}{
+		{"id before stream url", playlistfile.Entry{ID: 3, Identifier: "https://api.example.com/audio/42/stream"}, []uint{3, 42}},
+		{"id only", playlistfile.Entry{ID: 3, Location: "https://cdn.example.com/a.mp3"}, []uint{3}},
+		{"identifier before location", playlistfile.Entry{
+			Identifier: "https://api.example.com/audio/42/stream",
+			Location:   "https://api.example.com/audio/7/stream",
+		}, []uint{42}},
+		{"location when identifier is foreign", playlistfile.Entry{
+			Identifier: "https://evil.example.net/audio/42/stream",
+			Location:   "https://api.example.com/audio/7/stream",
+		}, []uint{7}},
+		{"nothing to go by", playlistfile.Entry{Title: "Rain", Location: "rain.mp3"}, []uint{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := importCandidates(base, tt.entry); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("importCandidates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,9 @@ package controllers
 import (
 	"backend/internal/initializers"
 	"backend/internal/models"
+	"backend/internal/pagination"
+	"backend/internal/serializers"
+	"backend/internal/utils"
 	"net/http"
 	"strconv"
 
@@ -26,51 +29,91 @@ func GetPublicProfile(c *gin.Context) {
 
 	initializers.DB.Model(&models.User_Relations{}).Where("following_id = ?", profileId).Count(&followersCount)
 	initializers.DB.Model(&models.User_Relations{}).Where("follower_id = ?", profileId).Count(&followingsCount)
+	likeCount, isFavorited := favoriteStatus(models.FavoriteUser, user.ID, viewerID(c))
 
 	c.JSON(http.StatusOK, gin.H{
 		"profile": gin.H{
-			"id":         user.ID,
-			"name":       user.Name,
-			"avatar":     user.AvatarURL,
-			"bio":        user.Bio,
-			"followers":  followersCount,
-			"followings": followingsCount,
+			"id":           user.ID,
+			"name":         user.Name,
+			"avatar":       utils.FileURL(user.AvatarURL),
+			"bio":          user.Bio,
+			"followers":    followersCount,
+			"followings":   followingsCount,
+			"like_count":   likeCount,
+			"is_favorited": isFavorited,
 		},
 	})
 }
 
-// List all followers for a user
+func relationCursor(relation models.User_Relations) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: relation.CreatedAt, ID: relation.ID}
+}
+
+func publicUser(user models.User) gin.H {
+	return gin.H{
+		"id":     user.ID,
+		"name":   user.Name,
+		"avatar": utils.FileURL(user.AvatarURL),
+		"bio":    user.Bio,
+	}
+}
+
+// List followers for a user, most recent first. Supports 'limit' and 'cursor' query parameters.
 func ListFollowers(c *gin.Context) {
 	userId := c.Param("userId")
 
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+
 	var relations []models.User_Relations
-	initializers.DB.Preload("Follower").Where("following_id = ?", userId).Find(&relations)
+	if err := initializers.DB.Preload("Follower").
+		Scopes(page.Scope("created_at", "id")).
+		Where("following_id = ?", userId).
+		Find(&relations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch followers"})
+		return
+	}
 
-	var followers []models.User
-	for _, relation := range relations {
-		followers = append(followers, relation.Follower)
+	relations, next := pagination.Trim(page, relations, relationCursor)
+
+	followers := make([]gin.H, len(relations))
+	for i, relation := range relations {
+		followers[i] = publicUser(relation.Follower)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"followers": followers,
-	})
+	c.JSON(http.StatusOK, pagination.Envelope(followers, next))
 }
 
-// List all followings for a user
+// List followings for a user, most recent first. Supports 'limit' and 'cursor' query parameters.
 func ListFollowing(c *gin.Context) {
 	userId := c.Param("userId")
 
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+
 	var relations []models.User_Relations
-	initializers.DB.Preload("Following").Where("follower_id = ?", userId).Find(&relations)
+	if err := initializers.DB.Preload("Following").
+		Scopes(page.Scope("created_at", "id")).
+		Where("follower_id = ?", userId).
+		Find(&relations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch following"})
+		return
+	}
+
+	relations, next := pagination.Trim(page, relations, relationCursor)
 
-	var followings []models.User
-	for _, relation := range relations {
-		followings = append(followings, relation.Following)
+	followings := make([]gin.H, len(relations))
+	for i, relation := range relations {
+		followings[i] = publicUser(relation.Following)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"following": followings,
-	})
+	c.JSON(http.StatusOK, pagination.Envelope(followings, next))
 }
 
 /*
@@ -97,22 +140,10 @@ func GetPersonalUploads(c *gin.Context) {
 		return
 	}
 
-	audioList := make([]map[string]interface{}, len(audios))
-	for i, item := range audios {
-		owner := models.User{}
-		initializers.DB.First(&owner, item.Owner)
-
-		audioList[i] = map[string]interface{}{
-			"id":       item.ID,
-			"title":    item.Title,
-			"category": item.Category,
-			"file":     item.AudioURL,
-			"poster":   item.CoverURL,
-			"owner": map[string]interface{}{
-				"name": owner.Name,
-				"id":   owner.ID,
-			},
-		}
+	audioList, err := serializers.Audios(initializers.DB, audios, viewerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load audio details"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"audios": audioList})
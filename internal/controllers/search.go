@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/utils"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+
+	// Matches are delimited with private use characters, which are stripped from the indexed text
+	// first, so snippets can be HTML-escaped before the delimiters become <mark> tags.
+	highlightStart   = "\uE000"
+	highlightStop    = "\uE001"
+	highlightMarkers = highlightStart + highlightStop
+
+	// headlineOptions configures ts_headline snippets.
+	headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxWords=25, MinWords=10, MaxFragments=2, FragmentDelimiter=\" … \""
+)
+
+var highlightTags = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+/*
+* highlight turns a ts_headline snippet into HTML that is safe to render: user text is escaped
+* and only the <mark> tags around matches remain markup
+ */
+func highlight(snippet string) string {
+	return highlightTags.Replace(html.EscapeString(snippet))
+}
+
+type audioSearchResult struct {
+	ID             uint
+	Title          string
+	Artist         string
+	Category       string
+	File           string
+	Poster         string
+	OwnerID        uint
+	OwnerName      string
+	Rank           float64
+	TitleHighlight string
+	Snippet        string
+	Total          int64
+}
+
+type playlistSearchResult struct {
+	ID             uint
+	Title          string
+	Poster         string
+	OwnerID        uint
+	OwnerName      string
+	Rank           float64
+	TitleHighlight string
+	Total          int64
+}
+
+type userSearchResult struct {
+	ID            uint
+	Name          string
+	Avatar        string
+	Rank          float64
+	NameHighlight string
+	Snippet       string
+	Total         int64
+}
+
+const audioSearchQuery = `
+SELECT a.id, a.name AS title, a.artist, a.category, a.audio_url AS file, a.cover_url AS poster,
+	u.id AS owner_id, u.name AS owner_name,
+	ts_rank(a.search_vector, q) AS rank,
+	ts_headline('english', translate(a.name, @markers, ''), q, @options) AS title_highlight,
+	ts_headline('english', translate(coalesce(a.about, ''), @markers, ''), q, @options) AS snippet,
+	count(*) OVER () AS total
+FROM audios a
+JOIN users u ON u.id = a.owner AND u.deleted_at IS NULL
+CROSS JOIN websearch_to_tsquery('english', @query) q
+WHERE a.deleted_at IS NULL AND a.search_vector @@ q
+	AND (a.visibility = 'public' OR a.owner = @viewer)
+	AND u.verified AND NOT u.banned
+ORDER BY rank DESC, a.id DESC
+LIMIT @limit OFFSET @offset`
+
+const playlistSearchQuery = `
+SELECT p.id, p.title, p.cover_url AS poster,
+	u.id AS owner_id, u.name AS owner_name,
+	ts_rank(p.search_vector, q) AS rank,
+	ts_headline('english', translate(p.title, @markers, ''), q, @options) AS title_highlight,
+	count(*) OVER () AS total
+FROM playlists p
+JOIN users u ON u.id = p.owner_id AND u.deleted_at IS NULL
+CROSS JOIN websearch_to_tsquery('english', @query) q
+WHERE p.deleted_at IS NULL AND p.search_vector @@ q
+	AND p.visibility = 'public'
+	AND u.verified AND NOT u.banned
+ORDER BY rank DESC, p.id DESC
+LIMIT @limit OFFSET @offset`
+
+// User names are indexed with the 'simple' configuration so they are not stemmed.
+const userSearchQuery = `
+SELECT u.id, u.name, u.avatar_url AS avatar,
+	ts_rank(u.search_vector, q) AS rank,
+	ts_headline('simple', translate(u.name, @markers, ''), q, @options) AS name_highlight,
+	ts_headline('english', translate(coalesce(u.bio, ''), @markers, ''), q, @options) AS snippet,
+	count(*) OVER () AS total
+FROM users u
+CROSS JOIN (SELECT websearch_to_tsquery('simple', @query) || websearch_to_tsquery('english', @query) AS q) terms
+WHERE u.deleted_at IS NULL AND u.search_vector @@ q
+	AND u.verified AND NOT u.banned
+ORDER BY rank DESC, u.id DESC
+LIMIT @limit OFFSET @offset`
+
+func searchGroup(items interface{}, total int64, page, limit int) gin.H {
+	return gin.H{
+		"items":    items,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+		"has_more": int64(page*limit) < total,
+	}
+}
+
+/*
+* GeneralSearch runs a ranked full-text search over audios, playlists and users.
+* Supports 'q', 'type' (audios, playlists, users or all), 'page' and 'limit' query parameters.
+* Only public playlists are returned, and never content of unverified or banned users.
+ */
+func GeneralSearch(c *gin.Context) {
+	query := c.Query("q")
+
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query cannot be empty"})
+		return
+	}
+
+	searchType := c.DefaultQuery("type", "all")
+	if searchType != "all" && searchType != "audios" && searchType != "playlists" && searchType != "users" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search type"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSearchLimit)))
+	if err != nil || limit < 1 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	args := map[string]interface{}{
+		"query":   query,
+		"options": headlineOptions,
+		"markers": highlightMarkers,
+		"limit":   limit,
+		"offset":  (page - 1) * limit,
+		"viewer":  viewerID(c),
+	}
+
+	results := gin.H{"query": query}
+
+	if searchType == "all" || searchType == "audios" {
+		var audios []audioSearchResult
+		if err := initializers.DB.Raw(audioSearchQuery, args).Scan(&audios).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed in audios"})
+			return
+		}
+
+		var total int64
+		items := make([]gin.H, len(audios))
+		for i, audio := range audios {
+			total = audio.Total
+			items[i] = gin.H{
+				"id":              audio.ID,
+				"title":           audio.Title,
+				"artist":          audio.Artist,
+				"category":        audio.Category,
+				"file":            utils.FileURL(audio.File),
+				"poster":          utils.FileURL(audio.Poster),
+				"rank":            audio.Rank,
+				"title_highlight": highlight(audio.TitleHighlight),
+				"snippet":         highlight(audio.Snippet),
+				"owner": gin.H{
+					"id":   audio.OwnerID,
+					"name": audio.OwnerName,
+				},
+			}
+		}
+		results["audios"] = searchGroup(items, total, page, limit)
+	}
+
+	if searchType == "all" || searchType == "playlists" {
+		var playlists []playlistSearchResult
+		if err := initializers.DB.Raw(playlistSearchQuery, args).Scan(&playlists).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed in playlists"})
+			return
+		}
+
+		var total int64
+		items := make([]gin.H, len(playlists))
+		for i, playlist := range playlists {
+			total = playlist.Total
+			items[i] = gin.H{
+				"id":              playlist.ID,
+				"title":           playlist.Title,
+				"poster":          utils.FileURL(playlist.Poster),
+				"rank":            playlist.Rank,
+				"title_highlight": highlight(playlist.TitleHighlight),
+				"owner": gin.H{
+					"id":   playlist.OwnerID,
+					"name": playlist.OwnerName,
+				},
+			}
+		}
+		results["playlists"] = searchGroup(items, total, page, limit)
+	}
+
+	if searchType == "all" || searchType == "users" {
+		var users []userSearchResult
+		if err := initializers.DB.Raw(userSearchQuery, args).Scan(&users).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed in users"})
+			return
+		}
+
+		var total int64
+		items := make([]gin.H, len(users))
+		for i, user := range users {
+			total = user.Total
+			items[i] = gin.H{
+				"id":             user.ID,
+				"name":           user.Name,
+				"avatar":         utils.FileURL(user.Avatar),
+				"rank":           user.Rank,
+				"name_highlight": highlight(user.NameHighlight),
+				"snippet":        highlight(user.Snippet),
+			}
+		}
+		results["users"] = searchGroup(items, total, page, limit)
+	}
+
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,25 @@
+package controllers
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name    string
+		snippet string
+		want    string
+	}{
+		{"plain text", "lofi beats", "lofi beats"},
+		{"match", "lofi " + highlightStart + "beats" + highlightStop, "lofi <mark>beats</mark>"},
+		{"markup is escaped", `<img src=x onerror="alert(1)">`, "&lt;img src=x onerror=&#34;alert(1)&#34;&gt;"},
+		{"mark tags in text are escaped", "<mark>" + highlightStart + "x" + highlightStop + "</mark>", "&lt;mark&gt;<mark>x</mark>&lt;/mark&gt;"},
+		{"match inside escaped text", "a & " + highlightStart + "b" + highlightStop + " <c>", "a &amp; <mark>b</mark> &lt;c&gt;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlight(tt.snippet); got != tt.want {
+				t.Errorf("highlight() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var errRefreshTokenReused = errors.New("refresh token reuse detected")
+
+type tokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"`
+	SessionID    string `json:"sessionId"`
+}
+
+/*
+* issueTokenPair signs a new access token and mints a refresh token for the given session family.
+* Only the hash of the refresh token is stored.
+ */
+func issueTokenPair(tx *gorm.DB, userID uint, family, device, ip string) (*tokenPair, error) {
+	now := time.Now()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userId": userID,
+		"sid":    family,
+		"exp":    now.Add(accessTokenTTL).Unix(),
+	})
+
+	accessToken, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := utils.GenerateRandomHexString(32)
+	if refreshToken == "" {
+		return nil, errors.New("failed to generate refresh token")
+	}
+
+	records := []models.Token{
+		{
+			UserID:     userID,
+			Token:      accessToken,
+			Type:       models.TokenTypeAccess,
+			Family:     family,
+			Device:     device,
+			IP:         ip,
+			LastSeenAt: now,
+			ExpiresAt:  now.Add(accessTokenTTL),
+		},
+		{
+			UserID:     userID,
+			Token:      utils.HashToken(refreshToken),
+			Type:       models.TokenTypeRefresh,
+			Family:     family,
+			Device:     device,
+			IP:         ip,
+			LastSeenAt: now,
+			ExpiresAt:  now.Add(refreshTokenTTL),
+		},
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return &tokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessTokenTTL.Seconds()),
+		SessionID:    family,
+	}, nil
+}
+
+/*
+* revokeSession removes every access and refresh token that belongs to a session family
+ */
+func revokeSession(tx *gorm.DB, userID uint, family string) error {
+	return tx.Where("user_id = ? AND family = ?", userID, family).Delete(&models.Token{}).Error
+}
+
+func requestDevice(c *gin.Context) string {
+	if device := c.GetHeader("X-Device-Name"); device != "" {
+		return device
+	}
+	return c.Request.UserAgent()
+}
+
+/*
+* RefreshToken exchanges a valid refresh token for a new access/refresh pair.
+* The presented refresh token is retired; presenting a retired token again revokes the whole session.
+ */
+func RefreshToken(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token not provided"})
+		return
+	}
+
+	var pair *tokenPair
+	// The session to revoke is remembered and revoked after the transaction, since
+	// returning an error from the transaction would roll the revocation back.
+	var compromised *models.Token
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.Token
+		if err := tx.Unscoped().
+			Where("token = ? AND type = ?", utils.HashToken(req.RefreshToken), models.TokenTypeRefresh).
+			First(&current).Error; err != nil {
+			return err
+		}
+
+		if current.DeletedAt.Valid {
+			compromised = &current
+			return errRefreshTokenReused
+		}
+
+		if time.Now().After(current.ExpiresAt) {
+			compromised = &current
+			return gorm.ErrRecordNotFound
+		}
+
+		// Retire the old refresh token and any access token still live in the session.
+		if err := tx.Where("family = ? AND type = ?", current.Family, models.TokenTypeAccess).Delete(&models.Token{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&current)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			compromised = &current
+			return errRefreshTokenReused
+		}
+
+		var err error
+		pair, err = issueTokenPair(tx, current.UserID, current.Family, requestDevice(c), c.ClientIP())
+		return err
+	})
+
+	if compromised != nil {
+		if revokeErr := revokeSession(initializers.DB, compromised.UserID, compromised.Family); revokeErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+			return
+		}
+	}
+
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, pair)
+	case errors.Is(err, errRefreshTokenReused):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token already used, session revoked"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+	}
+}
+
+/*
+* ListSessions returns the active sign-in sessions of the authenticated user
+ */
+func ListSessions(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var currentFamily string
+	if token, ok := c.Get("token"); ok {
+		if tokenModel, ok := token.(*models.Token); ok {
+			currentFamily = tokenModel.Family
+		}
+	}
+
+	var refreshTokens []models.Token
+	if err := initializers.DB.
+		Where("user_id = ? AND type = ? AND expires_at > ?", userModel.ID, models.TokenTypeRefresh, time.Now()).
+		Order("last_seen_at desc").
+		Find(&refreshTokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	sessions := make([]gin.H, len(refreshTokens))
+	for i, token := range refreshTokens {
+		sessions[i] = gin.H{
+			"id":         token.Family,
+			"device":     token.Device,
+			"ip":         token.IP,
+			"last_seen":  token.LastSeenAt,
+			"expires_at": token.ExpiresAt,
+			"current":    token.Family == currentFamily,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+/*
+* RevokeSession signs the authenticated user out of one of their sessions
+ */
+func RevokeSession(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	sessionID := c.Param("sessionId")
+
+	result := initializers.DB.Where("user_id = ? AND family = ?", userModel.ID, sessionID).Delete(&models.Token{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+/*
+* RevokeOtherSessions signs the authenticated user out everywhere except the current session
+ */
+func RevokeOtherSessions(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	query := initializers.DB.Where("user_id = ?", userModel.ID)
+	if token, ok := c.Get("token"); ok {
+		if tokenModel, ok := token.(*models.Token); ok {
+			if tokenModel.Family != "" {
+				query = query.Where("family IS NULL OR family <> ?", tokenModel.Family)
+			} else {
+				query = query.Where("id <> ?", tokenModel.ID)
+			}
+		}
+	}
+
+	if err := query.Delete(&models.Token{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Other sessions revoked successfully"})
+}
@@ -0,0 +1,360 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/models"
+	"backend/internal/serializers"
+	"backend/internal/utils"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	errShareLinkInactive = errors.New("share link is no longer active")
+	errShareLinkPassword = errors.New("share link password is incorrect")
+)
+
+// shareCredentials are the share token and password sent with a request.
+type shareCredentials struct {
+	Token    string
+	Password string
+}
+
+/*
+* requestShare returns the share credentials of a request. The token comes from the 'share' query parameter
+* or the X-Share-Token header, the password of protected links from the X-Share-Password header.
+ */
+func requestShare(c *gin.Context) shareCredentials {
+	token := c.Query("share")
+	if token == "" {
+		token = c.GetHeader("X-Share-Token")
+	}
+	return shareCredentials{Token: token, Password: c.GetHeader("X-Share-Password")}
+}
+
+/*
+* checkShareLink verifies that a link is active and that the password matches when it has one
+ */
+func checkShareLink(link *models.ShareLink, password string) error {
+	if !link.Active(time.Now()) {
+		return errShareLinkInactive
+	}
+	if link.PasswordHash != "" && bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+		return errShareLinkPassword
+	}
+	return nil
+}
+
+/*
+* consumeShareLink counts one use of a link. It reports false when the link has already been used MaxUses times,
+* also when concurrent requests race for the last use.
+ */
+func consumeShareLink(link *models.ShareLink) (bool, error) {
+	result := initializers.DB.Model(&models.ShareLink{}).
+		Where("id = ? AND (max_uses IS NULL OR uses < max_uses)", link.ID).
+		UpdateColumn("uses", gorm.Expr("uses + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+/*
+* findShareLink returns the link the credentials hold for a resource when it is active and its password matches.
+* Uses are only counted by OpenShareLink, so reading with the token does not use the link up.
+ */
+func findShareLink(resourceType string, resourceID uint, share shareCredentials) (*models.ShareLink, bool) {
+	if share.Token == "" {
+		return nil, false
+	}
+
+	var link models.ShareLink
+	if err := initializers.DB.
+		Where("token = ? AND resource_type = ? AND resource_id = ?", share.Token, resourceType, resourceID).
+		First(&link).Error; err != nil {
+		return nil, false
+	}
+	if checkShareLink(&link, share.Password) != nil {
+		return nil, false
+	}
+	return &link, true
+}
+
+/*
+* shareLinkGrants reports whether the credentials hold a valid share link for the playlist.
+* The standing link of an unlisted playlist only counts while the playlist is unlisted.
+ */
+func shareLinkGrants(playlist *models.Playlist, share shareCredentials) bool {
+	link, ok := findShareLink(models.ShareResourcePlaylist, playlist.ID, share)
+	return ok && (!link.Unlisted || playlist.Visibility == models.PlaylistUnlisted)
+}
+
+/*
+* ownsShareResource reports whether the user owns the playlist or audio a link would point to
+ */
+func ownsShareResource(resourceType string, resourceID, userID uint) bool {
+	var count int64
+	switch resourceType {
+	case models.ShareResourcePlaylist:
+		initializers.DB.Model(&models.Playlist{}).Where("id = ? AND owner_id = ?", resourceID, userID).Count(&count)
+	case models.ShareResourceAudio:
+		initializers.DB.Model(&models.Audio{}).Where("id = ? AND owner = ?", resourceID, userID).Count(&count)
+	}
+	return count > 0
+}
+
+func shareLinkResponse(c *gin.Context, link models.ShareLink) gin.H {
+	return gin.H{
+		"id":            link.ID,
+		"token":         link.Token,
+		"url":           apiBaseURL(c) + "/share/" + link.Token,
+		"resource_type": link.ResourceType,
+		"resource_id":   link.ResourceID,
+		"expires_at":    link.ExpiresAt,
+		"max_uses":      link.MaxUses,
+		"uses":          link.Uses,
+		"has_password":  link.PasswordHash != "",
+		"revoked_at":    link.RevokedAt,
+		"unlisted":      link.Unlisted,
+		"active":        link.Active(time.Now()) && !link.Exhausted(),
+		"created_at":    link.CreatedAt,
+	}
+}
+
+/*
+* CreateShareLink creates a link granting read access to a playlist or audio owned by the user.
+* It expects a JSON payload with 'resourceType' (playlist or audio), 'resourceId' and optional
+* 'expiresIn' (seconds), 'maxUses' and 'password'.
+ */
+func CreateShareLink(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var req models.CreateShareLink
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if validationErr := Validate.Struct(req); validationErr != nil {
+		c.Error(validationErr)
+		return
+	}
+
+	if !ownsShareResource(req.ResourceType, req.ResourceID, userModel.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found or not owned by user"})
+		return
+	}
+
+	link := models.ShareLink{
+		Token:        utils.GenerateRandomHexString(24),
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		OwnerID:      userModel.ID,
+		MaxUses:      req.MaxUses,
+	}
+
+	if req.ExpiresIn != nil {
+		expiresAt := time.Now().Add(time.Duration(*req.ExpiresIn) * time.Second)
+		link.ExpiresAt = &expiresAt
+	}
+
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+			return
+		}
+		link.PasswordHash = string(hash)
+	}
+
+	if err := initializers.DB.Create(&link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Share link created successfully",
+		"link":    shareLinkResponse(c, link),
+	})
+}
+
+/*
+* ListShareLinks lists the share links created by the user, newest first.
+* Supports optional 'resourceType' and 'resourceId' query parameters to narrow the list.
+ */
+func ListShareLinks(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	query := initializers.DB.Where("owner_id = ?", userModel.ID)
+	if resourceType := c.Query("resourceType"); resourceType != "" {
+		query = query.Where("resource_type = ?", resourceType)
+	}
+	if resourceID := c.Query("resourceId"); resourceID != "" {
+		query = query.Where("resource_id = ?", resourceID)
+	}
+
+	var links []models.ShareLink
+	if err := query.Order("created_at DESC, id DESC").Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch share links"})
+		return
+	}
+
+	response := make([]gin.H, len(links))
+	for i, link := range links {
+		response[i] = shareLinkResponse(c, link)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"links": response})
+}
+
+/*
+* RevokeShareLink stops a share link from granting access.
+* It uses a path parameter 'linkId'. Requires ownership of the link.
+ */
+func RevokeShareLink(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var link models.ShareLink
+	if err := initializers.DB.Where("id = ? AND owner_id = ?", c.Param("linkId"), userModel.ID).First(&link).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+
+	if link.RevokedAt == nil {
+		now := time.Now()
+		if err := initializers.DB.Model(&link).Update("revoked_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share link"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Share link revoked successfully"})
+}
+
+/*
+* OpenShareLink resolves a share token to the shared playlist or audio and counts one use of the link.
+* It uses a path parameter 'token'; protected links need the password in the X-Share-Password header.
+* The response carries everything needed to play the content, so links limited to a single use still work.
+* Later reads of the resource accept the token through the 'share' query parameter or X-Share-Token header.
+ */
+func OpenShareLink(c *gin.Context) {
+	var link models.ShareLink
+	if err := initializers.DB.Where("token = ?", c.Param("token")).First(&link).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+
+	switch err := checkShareLink(&link, c.GetHeader("X-Share-Password")); err {
+	case errShareLinkInactive:
+		c.JSON(http.StatusGone, gin.H{"error": "Share link has expired or was revoked"})
+		return
+	case errShareLinkPassword:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Share link password required or incorrect"})
+		return
+	}
+
+	var playlist models.Playlist
+	var audio models.Audio
+	switch link.ResourceType {
+	case models.ShareResourcePlaylist:
+		if initializers.DB.First(&playlist, link.ResourceID).Error != nil ||
+			(link.Unlisted && playlist.Visibility != models.PlaylistUnlisted) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+			return
+		}
+	case models.ShareResourceAudio:
+		if initializers.DB.First(&audio, link.ResourceID).Error != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+			return
+		}
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+
+	consumed, err := consumeShareLink(&link)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open share link"})
+		return
+	}
+	if !consumed {
+		c.JSON(http.StatusGone, gin.H{"error": "Share link has reached its maximum number of uses"})
+		return
+	}
+
+	if link.ResourceType == models.ShareResourceAudio {
+		audioList, err := serializers.Audios(initializers.DB, []models.Audio{audio}, viewerID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load audio details"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"resource_type": link.ResourceType,
+			"audio":         audioList[0],
+			"stream_url":    streamURL(apiBaseURL(c), audio.ID) + "?share=" + link.Token,
+		})
+		return
+	}
+
+	audios, err := playlistTrackAudios(initializers.DB, &playlist)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlist tracks"})
+		return
+	}
+	audios = filterVisibleAudios(audios, viewerID(c))
+
+	audioList, err := serializers.Audios(initializers.DB, audios, viewerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load audio details"})
+		return
+	}
+
+	var owner models.User
+	initializers.DB.First(&owner, playlist.Owner)
+
+	c.JSON(http.StatusOK, gin.H{
+		"resource_type": link.ResourceType,
+		"playlist": gin.H{
+			"id":         playlist.ID,
+			"title":      playlist.Title,
+			"coverurl":   utils.FileURL(playlist.DisplayCoverURL()),
+			"owner_name": owner.Name,
+			"owner_id":   owner.ID,
+			"song_count": len(audios),
+		},
+		"audios": audioList,
+	})
+}
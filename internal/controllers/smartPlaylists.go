@@ -0,0 +1,345 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/models"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// smartPlaylistTTL is how long the tracks of a smart playlist are reused before its rules run again.
+// Edits to the rules bump the playlist version, which invalidates the cached tracks right away.
+const smartPlaylistTTL = 5 * time.Minute
+
+type cachedSmartPlaylist struct {
+	version  uint
+	audioIDs []uint
+	expires  time.Time
+}
+
+var smartPlaylistCache = struct {
+	sync.Mutex
+	entries map[uint]cachedSmartPlaylist
+}{entries: make(map[uint]cachedSmartPlaylist)}
+
+/*
+* compileSmartRule turns a validated rule into a SQL condition over the audios table.
+* ownerID is the playlist owner, who "followed" and "favorited_within" are relative to.
+ */
+func compileSmartRule(rule models.SmartRule, ownerID uint) (string, []interface{}) {
+	columns := map[string]string{
+		"category": "audios.category",
+		"genre":    "audios.genre",
+		"artist":   "audios.artist",
+		"title":    "audios.name",
+		"owner":    "audios.owner",
+	}
+
+	switch rule.Field {
+	case "followed":
+		var follows bool
+		json.Unmarshal(rule.Value, &follows)
+		condition := "audios.owner IN (SELECT following_id FROM user_relations WHERE follower_id = ? AND deleted_at IS NULL)"
+		if !follows {
+			condition = "audios.owner NOT IN (SELECT following_id FROM user_relations WHERE follower_id = ? AND deleted_at IS NULL)"
+		}
+		return condition, []interface{}{ownerID}
+
+	case "favorited_within":
+		var days float64
+		json.Unmarshal(rule.Value, &days)
+		since := time.Now().Add(-time.Duration(days * float64(24*time.Hour)))
+		return "audios.id IN (SELECT target_id FROM favorites WHERE user_id = ? AND target_type = 'audio' AND created_at >= ?)", []interface{}{ownerID, since}
+
+	case "uploaded_within":
+		var days float64
+		json.Unmarshal(rule.Value, &days)
+		since := time.Now().Add(-time.Duration(days * float64(24*time.Hour)))
+		return "audios.created_at >= ?", []interface{}{since}
+
+	case "duration":
+		if rule.Op == "between" {
+			var bounds []float64
+			json.Unmarshal(rule.Value, &bounds)
+			return "audios.duration BETWEEN ? AND ?", []interface{}{bounds[0], bounds[1]}
+		}
+		var seconds float64
+		json.Unmarshal(rule.Value, &seconds)
+		if rule.Op == "gt" {
+			return "audios.duration > ?", []interface{}{seconds}
+		}
+		return "audios.duration < ?", []interface{}{seconds}
+	}
+
+	column := columns[rule.Field]
+	switch rule.Op {
+	case "contains":
+		var text string
+		json.Unmarshal(rule.Value, &text)
+		return column + " ILIKE ?", []interface{}{"%" + escapeLike(text) + "%"}
+	case "in", "not_in":
+		var values interface{}
+		if rule.Field == "owner" {
+			var ids []uint
+			json.Unmarshal(rule.Value, &ids)
+			values = ids
+		} else {
+			var names []string
+			json.Unmarshal(rule.Value, &names)
+			values = names
+		}
+		if rule.Op == "not_in" {
+			return column + " NOT IN ?", []interface{}{values}
+		}
+		return column + " IN ?", []interface{}{values}
+	}
+
+	return "FALSE", nil
+}
+
+func combineSmartConditions(match string, rules []models.SmartRule, ownerID uint) (string, []interface{}) {
+	joiner := " AND "
+	if match == "any" {
+		joiner = " OR "
+	}
+
+	conditions := make([]string, 0, len(rules))
+	var args []interface{}
+	for _, rule := range rules {
+		condition, ruleArgs := compileSmartRule(rule, ownerID)
+		conditions = append(conditions, "("+condition+")")
+		args = append(args, ruleArgs...)
+	}
+	return strings.Join(conditions, joiner), args
+}
+
+/*
+* smartPlaylistAudios returns the audios matching the rules of a smart playlist. The matches of each
+* playlist version are kept for smartPlaylistTTL, so details, tracks, exports and covers read within
+* that window share one evaluation and see the same order, even for random sorts.
+ */
+func smartPlaylistAudios(db *gorm.DB, playlist *models.Playlist) ([]models.Audio, error) {
+	if audioIDs, ok := cachedSmartPlaylistIDs(playlist); ok {
+		return audiosInOrder(db, audioIDs)
+	}
+
+	audios, err := evaluateSmartRules(db, playlist)
+	if err != nil {
+		return nil, err
+	}
+
+	audioIDs := make([]uint, len(audios))
+	for i, audio := range audios {
+		audioIDs[i] = audio.ID
+	}
+	cacheSmartPlaylistIDs(playlist, audioIDs)
+
+	return audios, nil
+}
+
+func cachedSmartPlaylistIDs(playlist *models.Playlist) ([]uint, bool) {
+	smartPlaylistCache.Lock()
+	defer smartPlaylistCache.Unlock()
+
+	cached, ok := smartPlaylistCache.entries[playlist.ID]
+	if !ok || cached.version != playlist.Version || time.Now().After(cached.expires) {
+		return nil, false
+	}
+	return cached.audioIDs, true
+}
+
+func cacheSmartPlaylistIDs(playlist *models.Playlist, audioIDs []uint) {
+	smartPlaylistCache.Lock()
+	defer smartPlaylistCache.Unlock()
+
+	now := time.Now()
+	for id, cached := range smartPlaylistCache.entries {
+		if now.After(cached.expires) {
+			delete(smartPlaylistCache.entries, id)
+		}
+	}
+	smartPlaylistCache.entries[playlist.ID] = cachedSmartPlaylist{
+		version:  playlist.Version,
+		audioIDs: audioIDs,
+		expires:  now.Add(smartPlaylistTTL),
+	}
+}
+
+/*
+* audiosInOrder loads audios by ID in the given order, leaving out the ones that no longer exist
+ */
+func audiosInOrder(db *gorm.DB, audioIDs []uint) ([]models.Audio, error) {
+	var found []models.Audio
+	if len(audioIDs) > 0 {
+		if err := db.Where("id IN ?", audioIDs).Find(&found).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	byID := make(map[uint]models.Audio, len(found))
+	for _, audio := range found {
+		byID[audio.ID] = audio
+	}
+
+	audios := make([]models.Audio, 0, len(audioIDs))
+	for _, id := range audioIDs {
+		if audio, ok := byID[id]; ok {
+			audios = append(audios, audio)
+		}
+	}
+	return audios, nil
+}
+
+/*
+* evaluateSmartRules runs the rules of a smart playlist against the audios table
+ */
+func evaluateSmartRules(db *gorm.DB, playlist *models.Playlist) ([]models.Audio, error) {
+	var audios []models.Audio
+	if playlist.Rules == nil {
+		return audios, nil
+	}
+	rules := *playlist.Rules
+
+	conditions := make([]string, 0, len(rules.Groups)+1)
+	var args []interface{}
+
+	if len(rules.Rules) > 0 {
+		condition, ruleArgs := combineSmartConditions(rules.Match, rules.Rules, playlist.Owner)
+		conditions = append(conditions, "("+condition+")")
+		args = append(args, ruleArgs...)
+	}
+	for _, group := range rules.Groups {
+		condition, ruleArgs := combineSmartConditions(group.Match, group.Rules, playlist.Owner)
+		conditions = append(conditions, "("+condition+")")
+		args = append(args, ruleArgs...)
+	}
+
+	joiner := " AND "
+	if rules.Match == "any" {
+		joiner = " OR "
+	}
+
+	query := db.Model(&models.Audio{}).Scopes(visibleAudios(playlist.Owner)).Where(strings.Join(conditions, joiner), args...)
+
+	switch rules.Sort {
+	case "oldest":
+		query = query.Order("audios.created_at, audios.id")
+	case "title":
+		query = query.Order("audios.name, audios.id")
+	case "duration":
+		query = query.Order("audios.duration, audios.id")
+	case "most_played":
+		query = query.Order("(SELECT count(*) FROM histories WHERE histories.audio_id = audios.id AND histories.deleted_at IS NULL) DESC, audios.id DESC")
+	case "random":
+		query = query.Order("RANDOM()")
+	default:
+		query = query.Order("audios.created_at DESC, audios.id DESC")
+	}
+
+	err := query.Limit(rules.Limit).Find(&audios).Error
+	return audios, err
+}
+
+/*
+* GetSmartPlaylistRules returns the rules of a smart playlist
+ */
+func GetSmartPlaylistRules(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var playlist models.Playlist
+	if err := initializers.DB.First(&playlist, c.Param("playlistId")).Error; err != nil || playlistRole(&playlist, userModel.ID) == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+		return
+	}
+
+	if playlist.Visibility != models.PlaylistAuto {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Playlist is not a smart playlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": playlist.Rules})
+}
+
+/*
+* UpdateSmartPlaylistRules sets the rules of a playlist and turns it into a smart ("auto") playlist.
+* It expects a JSON payload with 'match', 'rules', optional 'groups', 'sort' and 'limit'.
+* Requires ownership of the playlist.
+ */
+func UpdateSmartPlaylistRules(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var rules models.SmartPlaylistRules
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if err := rules.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var playlist models.Playlist
+	if err := initializers.DB.Where("id = ? AND owner_id = ?", c.Param("playlistId"), userModel.ID).First(&playlist).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found or not owned by user"})
+		return
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := bumpPlaylistVersion(tx, &playlist, nil); err != nil {
+			return err
+		}
+		if err := tx.Model(&playlist).Updates(map[string]interface{}{
+			"visibility": models.PlaylistAuto,
+			"rules":      &rules,
+		}).Error; err != nil {
+			return err
+		}
+		return logPlaylistActivity(tx, playlist.ID, userModel.ID, "update_rules", gin.H{"rules": rules})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update playlist rules"})
+		return
+	}
+
+	playlist.Rules = &rules
+	audios, err := smartPlaylistAudios(initializers.DB, &playlist)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate playlist rules"})
+		return
+	}
+
+	refreshPlaylistCoverAsync(playlist.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Playlist rules updated successfully",
+		"rules":       rules,
+		"track_count": len(audios),
+	})
+}
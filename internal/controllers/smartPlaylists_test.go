@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"backend/internal/models"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompileSmartRule(t *testing.T) {
+	const ownerID = 7
+
+	tests := []struct {
+		name      string
+		rule      models.SmartRule
+		condition string
+		args      []interface{}
+	}{
+		{
+			name:      "category in",
+			rule:      models.SmartRule{Field: "category", Op: "in", Value: json.RawMessage(`["chill","focus"]`)},
+			condition: "audios.category IN ?",
+			args:      []interface{}{[]string{"chill", "focus"}},
+		},
+		{
+			name:      "genre not in",
+			rule:      models.SmartRule{Field: "genre", Op: "not_in", Value: json.RawMessage(`["metal"]`)},
+			condition: "audios.genre NOT IN ?",
+			args:      []interface{}{[]string{"metal"}},
+		},
+		{
+			name:      "title contains escapes wildcards",
+			rule:      models.SmartRule{Field: "title", Op: "contains", Value: json.RawMessage(`"100%_live"`)},
+			condition: "audios.name ILIKE ?",
+			args:      []interface{}{`%100\%\_live%`},
+		},
+		{
+			name:      "owner in",
+			rule:      models.SmartRule{Field: "owner", Op: "in", Value: json.RawMessage(`[3,4]`)},
+			condition: "audios.owner IN ?",
+			args:      []interface{}{[]uint{3, 4}},
+		},
+		{
+			name:      "followed",
+			rule:      models.SmartRule{Field: "followed", Op: "is", Value: json.RawMessage(`true`)},
+			condition: "audios.owner IN (SELECT following_id FROM user_relations WHERE follower_id = ? AND deleted_at IS NULL)",
+			args:      []interface{}{uint(ownerID)},
+		},
+		{
+			name:      "not followed",
+			rule:      models.SmartRule{Field: "followed", Op: "is", Value: json.RawMessage(`false`)},
+			condition: "audios.owner NOT IN (SELECT following_id FROM user_relations WHERE follower_id = ? AND deleted_at IS NULL)",
+			args:      []interface{}{uint(ownerID)},
+		},
+		{
+			name:      "duration between",
+			rule:      models.SmartRule{Field: "duration", Op: "between", Value: json.RawMessage(`[60,180]`)},
+			condition: "audios.duration BETWEEN ? AND ?",
+			args:      []interface{}{60.0, 180.0},
+		},
+		{
+			name:      "duration greater than",
+			rule:      models.SmartRule{Field: "duration", Op: "gt", Value: json.RawMessage(`240`)},
+			condition: "audios.duration > ?",
+			args:      []interface{}{240.0},
+		},
+		{
+			name:      "duration less than",
+			rule:      models.SmartRule{Field: "duration", Op: "lt", Value: json.RawMessage(`30`)},
+			condition: "audios.duration < ?",
+			args:      []interface{}{30.0},
+		},
+		{
+			name:      "unknown operator",
+			rule:      models.SmartRule{Field: "genre", Op: "matches", Value: json.RawMessage(`"x"`)},
+			condition: "FALSE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, args := compileSmartRule(tt.rule, ownerID)
+			if condition != tt.condition {
+				t.Errorf("condition = %q, want %q", condition, tt.condition)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestCompileSmartRuleWithin(t *testing.T) {
+	tests := []struct {
+		name      string
+		rule      models.SmartRule
+		condition string
+		days      float64
+	}{
+		{
+			name:      "uploaded within",
+			rule:      models.SmartRule{Field: "uploaded_within", Op: "lt", Value: json.RawMessage(`7`)},
+			condition: "audios.created_at >= ?",
+			days:      7,
+		},
+		{
+			name:      "favorited within",
+			rule:      models.SmartRule{Field: "favorited_within", Op: "lt", Value: json.RawMessage(`0.5`)},
+			condition: "audios.id IN (SELECT target_id FROM favorites WHERE user_id = ? AND target_type = 'audio' AND created_at >= ?)",
+			days:      0.5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now()
+			condition, args := compileSmartRule(tt.rule, 7)
+			after := time.Now()
+
+			if condition != tt.condition {
+				t.Errorf("condition = %q, want %q", condition, tt.condition)
+			}
+			since, ok := args[len(args)-1].(time.Time)
+			if !ok {
+				t.Fatalf("last arg = %#v, want a time", args[len(args)-1])
+			}
+			window := time.Duration(tt.days * float64(24*time.Hour))
+			if since.Before(before.Add(-window)) || since.After(after.Add(-window)) {
+				t.Errorf("since = %v, want %v before now", since, window)
+			}
+		})
+	}
+}
+
+func TestCombineSmartConditions(t *testing.T) {
+	rules := []models.SmartRule{
+		{Field: "genre", Op: "in", Value: json.RawMessage(`["jazz"]`)},
+		{Field: "duration", Op: "gt", Value: json.RawMessage(`60`)},
+	}
+
+	tests := []struct {
+		match     string
+		condition string
+	}{
+		{"all", "(audios.genre IN ?) AND (audios.duration > ?)"},
+		{"any", "(audios.genre IN ?) OR (audios.duration > ?)"},
+	}
+
+	for _, tt := range tests {
+		condition, args := combineSmartConditions(tt.match, rules, 1)
+		if condition != tt.condition {
+			t.Errorf("combineSmartConditions(%q) = %q, want %q", tt.match, condition, tt.condition)
+		}
+		if len(args) != 2 {
+			t.Errorf("combineSmartConditions(%q) returned %d args, want 2", tt.match, len(args))
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSuggestLimit = 8
+	maxSuggestLimit     = 20
+	minSuggestLength    = 2
+
+	suggestTimeout    = 250 * time.Millisecond
+	suggestCacheTTL   = time.Minute
+	suggestCacheLimit = 1000
+)
+
+type suggestion struct {
+	Text  string  `json:"text" gorm:"column:value"`
+	Kind  string  `json:"kind"`
+	Score float64 `json:"score"`
+}
+
+// Candidates match by trigram similarity or by prefix; prefix matches get a boost.
+// Like search, only public audios of verified users that are not banned are considered. The CTE is inlined
+// so each branch can still use the trigram indexes.
+const suggestQuery = `
+WITH visible AS NOT MATERIALIZED (
+	SELECT a.name, a.artist, a.category FROM audios a
+	JOIN users u ON u.id = a.owner AND u.deleted_at IS NULL AND u.verified AND NOT u.banned
+	WHERE a.deleted_at IS NULL AND a.visibility = 'public'
+), candidates AS (
+	SELECT name AS value, 'title' AS kind FROM visible
+	WHERE name % @query OR name ILIKE @prefix
+	UNION ALL
+	SELECT artist, 'artist' FROM visible
+	WHERE artist <> '' AND (artist % @query OR artist ILIKE @prefix)
+	UNION ALL
+	SELECT category, 'category' FROM visible
+	WHERE category % @query OR category ILIKE @prefix
+)
+SELECT value, kind,
+	max(similarity(value, @query) + CASE WHEN value ILIKE @prefix THEN 0.5 ELSE 0 END) AS score
+FROM candidates
+GROUP BY value, kind
+ORDER BY score DESC, count(*) DESC, value
+LIMIT @limit`
+
+type suggestCacheEntry struct {
+	suggestions []suggestion
+	expiresAt   time.Time
+}
+
+// suggestCache keeps recent suggestions for hot prefixes in memory.
+type suggestCache struct {
+	mu      sync.Mutex
+	entries map[string]suggestCacheEntry
+}
+
+var suggestionCache = &suggestCache{entries: make(map[string]suggestCacheEntry)}
+
+func (sc *suggestCache) get(key string) ([]suggestion, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	entry, ok := sc.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(sc.entries, key)
+		return nil, false
+	}
+	return entry.suggestions, true
+}
+
+func (sc *suggestCache) set(key string, value []suggestion) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if len(sc.entries) >= suggestCacheLimit {
+		now := time.Now()
+		for k, entry := range sc.entries {
+			if now.After(entry.expiresAt) {
+				delete(sc.entries, k)
+			}
+		}
+		// Still full: drop arbitrary entries rather than grow without bound.
+		for k := range sc.entries {
+			if len(sc.entries) < suggestCacheLimit {
+				break
+			}
+			delete(sc.entries, k)
+		}
+	}
+
+	sc.entries[key] = suggestCacheEntry{suggestions: value, expiresAt: time.Now().Add(suggestCacheTTL)}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+/*
+* SearchSuggest returns autocomplete suggestions for the search box.
+* Supports 'q' and 'limit' query parameters. Misspelled input still matches through trigram similarity.
+ */
+func SearchSuggest(c *gin.Context) {
+	query := strings.ToLower(strings.Join(strings.Fields(c.Query("q")), " "))
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSuggestLimit)))
+	if err != nil || limit < 1 {
+		limit = defaultSuggestLimit
+	}
+	if limit > maxSuggestLimit {
+		limit = maxSuggestLimit
+	}
+
+	if len([]rune(query)) < minSuggestLength {
+		c.JSON(http.StatusOK, gin.H{"query": query, "suggestions": []suggestion{}})
+		return
+	}
+
+	cacheKey := strconv.Itoa(limit) + ":" + query
+	if cached, ok := suggestionCache.get(cacheKey); ok {
+		c.JSON(http.StatusOK, gin.H{"query": query, "suggestions": cached})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), suggestTimeout)
+	defer cancel()
+
+	results := []suggestion{}
+	err = initializers.DB.WithContext(ctx).Raw(suggestQuery, map[string]interface{}{
+		"query":  query,
+		"prefix": escapeLike(query) + "%",
+		"limit":  limit,
+	}).Scan(&results).Error
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// Suggestions are best effort; an empty list keeps the search box responsive.
+		c.JSON(http.StatusOK, gin.H{"query": query, "suggestions": []suggestion{}, "timed_out": true})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch suggestions"})
+		return
+	}
+
+	suggestionCache.set(cacheKey, results)
+
+	c.JSON(http.StatusOK, gin.H{"query": query, "suggestions": results})
+}
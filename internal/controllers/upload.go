@@ -0,0 +1,402 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// uploadLocks serialises chunk writes per upload session within this process.
+// Entries exist only for sessions that were loaded and are removed with the session.
+var uploadLocks sync.Map
+
+func uploadTTL() time.Duration {
+	return initializers.GetEnvDuration("UPLOAD_SESSION_TTL", 24*time.Hour)
+}
+
+func maxResumableSize() int64 {
+	return int64(initializers.GetEnvInt("UPLOAD_MAX_RESUMABLE_MB", 1024)) * middleware.Megabyte
+}
+
+func maxChunkSize() int64 {
+	return int64(initializers.GetEnvInt("UPLOAD_MAX_CHUNK_MB", 16)) * middleware.Megabyte
+}
+
+/*
+* partialUploadPath returns where the bytes received so far for a session are kept
+ */
+func partialUploadPath(id string) string {
+	dir := os.Getenv("UPLOAD_TMP_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "audify-uploads")
+	}
+	return filepath.Join(dir, id+".part")
+}
+
+/*
+* findUploadSession loads an unexpired upload session owned by the authenticated user
+ */
+func findUploadSession(c *gin.Context, userID uint) (*models.UploadSession, bool) {
+	var session models.UploadSession
+	if err := initializers.DB.Where("id = ? AND owner_id = ?", c.Param("uploadId"), userID).First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return nil, false
+	}
+
+	if session.IsExpired() {
+		removeUploadSession(&session)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return nil, false
+	}
+
+	return &session, true
+}
+
+/*
+* lockUploadSession loads an upload session of the user and holds its lock until release is called.
+* Concurrent requests on the same upload get a 423 rather than waiting.
+ */
+func lockUploadSession(c *gin.Context, userID uint) (*models.UploadSession, func(), bool) {
+	session, ok := findUploadSession(c, userID)
+	if !ok {
+		return nil, nil, false
+	}
+
+	lock, _ := uploadLocks.LoadOrStore(session.ID, &sync.Mutex{})
+	mutex := lock.(*sync.Mutex)
+	if !mutex.TryLock() {
+		c.JSON(http.StatusLocked, gin.H{"error": "Upload is busy"})
+		return nil, nil, false
+	}
+
+	// Reload under the lock, another request may have moved the offset or removed the upload.
+	if err := initializers.DB.Where("id = ?", session.ID).First(session).Error; err != nil {
+		mutex.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return nil, nil, false
+	}
+
+	return session, mutex.Unlock, true
+}
+
+func removeUploadSession(session *models.UploadSession) {
+	uploadLocks.Delete(session.ID)
+	if err := os.Remove(partialUploadPath(session.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Failed to remove partial upload %s: %v", session.ID, err)
+	}
+	initializers.DB.Delete(session)
+}
+
+func uploadSessionResponse(session *models.UploadSession) gin.H {
+	return gin.H{
+		"id":         session.ID,
+		"filename":   session.Filename,
+		"size":       session.Size,
+		"offset":     session.Offset,
+		"complete":   session.IsComplete(),
+		"expires_at": session.ExpiresAt,
+	}
+}
+
+/*
+* CreateUploadSession starts a resumable upload.
+* It expects a JSON payload with 'filename', 'size' and optionally 'contentType'.
+ */
+func CreateUploadSession(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	var req models.CreateUploadSession
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if validationErr := Validate.Struct(req); validationErr != nil {
+		c.Error(validationErr)
+		return
+	}
+
+	if req.Size > maxResumableSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large", "max_size": maxResumableSize()})
+		return
+	}
+
+	session := models.UploadSession{
+		ID:          uuid.New().String(),
+		Owner:       userModel.ID,
+		Filename:    filepath.Base(req.Filename),
+		ContentType: req.ContentType,
+		Size:        req.Size,
+		ExpiresAt:   time.Now().Add(uploadTTL()),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(partialUploadPath(session.ID)), 0o755); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+
+	if err := initializers.DB.Create(&session).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+
+	c.Header("Location", c.Request.URL.Path+"/"+session.ID)
+	c.JSON(http.StatusCreated, gin.H{"upload": uploadSessionResponse(&session)})
+}
+
+/*
+* GetUploadSession reports how many bytes of an upload have been received.
+* The offset is also returned in the Upload-Offset header so HEAD can be used.
+ */
+func GetUploadSession(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	session, ok := findUploadSession(c, userModel.ID)
+	if !ok {
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Size, 10))
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"upload": uploadSessionResponse(session)})
+}
+
+/*
+* UploadChunk appends the request body to an upload.
+* The Upload-Offset header must match the number of bytes already received.
+ */
+func UploadChunk(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid Upload-Offset header"})
+		return
+	}
+
+	session, release, ok := lockUploadSession(c, userModel.ID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	if offset != session.Offset {
+		c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload offset mismatch", "offset": session.Offset})
+		return
+	}
+
+	if session.IsComplete() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload is already complete"})
+		return
+	}
+
+	file, err := os.OpenFile(partialUploadPath(session.ID), os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write chunk"})
+		return
+	}
+	defer file.Close()
+
+	// Drop anything past the recorded offset left behind by an interrupted chunk.
+	if err := file.Truncate(session.Offset); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write chunk"})
+		return
+	}
+	if _, err := file.Seek(session.Offset, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write chunk"})
+		return
+	}
+
+	remaining := session.Size - session.Offset
+	limit := maxChunkSize()
+	if remaining < limit {
+		limit = remaining
+	}
+
+	written, err := io.Copy(file, io.LimitReader(c.Request.Body, limit+1))
+	if written > limit {
+		file.Truncate(session.Offset)
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Chunk exceeds the upload size or chunk limit", "max_chunk_size": limit})
+		return
+	}
+
+	// Keep whatever arrived before a dropped connection so the client can resume from there.
+	if syncErr := file.Sync(); syncErr != nil && err == nil {
+		err = syncErr
+	}
+
+	session.Offset += written
+	session.ExpiresAt = time.Now().Add(uploadTTL())
+	if saveErr := initializers.DB.Model(session).Updates(map[string]interface{}{
+		"upload_offset": session.Offset,
+		"expires_at":    session.ExpiresAt,
+	}).Error; saveErr != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save upload progress"})
+		return
+	}
+
+	if err != nil {
+		c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Chunk was interrupted", "offset": session.Offset})
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.JSON(http.StatusOK, gin.H{"upload": uploadSessionResponse(session)})
+}
+
+/*
+* CompleteUpload turns a fully received upload into an audio.
+* It expects the same form fields as CreateAudio, without 'audioFile'.
+ */
+func CompleteUpload(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	session, release, ok := lockUploadSession(c, userModel.ID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	if !session.IsComplete() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is not complete", "offset": session.Offset, "size": session.Size})
+		return
+	}
+
+	path := partialUploadPath(session.ID)
+	file, err := os.Open(path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open uploaded file"})
+		return
+	}
+
+	rule := middleware.FileRule{Allowed: middleware.AudioMIMETypes, MaxSize: maxResumableSize()}
+	uploadErr := middleware.CheckUploadedContent("audioFile", file, session.Size, rule)
+	file.Close()
+	if uploadErr != nil {
+		removeUploadSession(session)
+		c.Error(uploadErr)
+		return
+	}
+
+	createAudio(c, userModel, audioSource{
+		Filename:    session.Filename,
+		Size:        session.Size,
+		ContentType: session.ContentType,
+		Open: func() (io.ReadSeekCloser, error) {
+			return os.Open(path)
+		},
+	})
+
+	if c.Writer.Status() < http.StatusBadRequest {
+		removeUploadSession(session)
+	}
+}
+
+/*
+* CancelUpload discards an upload and the data received so far
+ */
+func CancelUpload(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	session, release, ok := lockUploadSession(c, userModel.ID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	removeUploadSession(session)
+	c.JSON(http.StatusOK, gin.H{"message": "Upload cancelled"})
+}
+
+/*
+* CleanupExpiredUploads removes abandoned upload sessions and their partial data
+ */
+func CleanupExpiredUploads() {
+	var sessions []models.UploadSession
+	if err := initializers.DB.Where("expires_at < ?", time.Now()).Find(&sessions).Error; err != nil {
+		log.Printf("Failed to query expired uploads: %v", err)
+		return
+	}
+
+	removed := 0
+	for i := range sessions {
+		// A request still writing a chunk holds the session lock; its upload is removed on a later run.
+		lock, _ := uploadLocks.LoadOrStore(sessions[i].ID, &sync.Mutex{})
+		mutex := lock.(*sync.Mutex)
+		if !mutex.TryLock() {
+			continue
+		}
+		removeUploadSession(&sessions[i])
+		mutex.Unlock()
+		removed++
+	}
+
+	if removed > 0 {
+		log.Printf("Removed %d expired uploads", removed)
+	}
+}
@@ -4,20 +4,25 @@ import (
 	"backend/internal/initializers"
 	"backend/internal/models"
 	"backend/internal/utils"
+	response "backend/pkg"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var Validate *validator.Validate
 
+var errResetTokenUsed = errors.New("password reset token already used")
+
 // Initialize the validator
 func init() {
 	Validate = validator.New()
@@ -50,15 +55,9 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	token := utils.GenerateToken(6)
-	verificationRecord := models.UserEmailVerification{
-		UserID:    newUser.ID,
-		Token:     token,
-		CreatedAt: time.Now(),
-	}
-
-	if result := initializers.DB.Create(&verificationRecord); result.Error != nil {
-		c.Error(result.Error)
+	token, err := issueEmailVerification(initializers.DB, newUser.ID)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -70,28 +69,88 @@ func CreateUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": newUser})
 }
 
+/*
+* issueEmailVerification replaces any pending OTP of the user with a fresh one and returns the plaintext code
+ */
+func issueEmailVerification(db *gorm.DB, userID uint) (string, error) {
+	token, err := utils.GenerateToken(6)
+	if err != nil {
+		return "", err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserEmailVerification{}).Error; err != nil {
+			return err
+		}
+
+		verificationRecord := models.UserEmailVerification{
+			UserID:    userID,
+			Token:     token,
+			CreatedAt: time.Now(),
+			ExpiresAt: time.Now().Add(initializers.GetEnvDuration("OTP_TTL", 10*time.Minute)),
+		}
+		return tx.Create(&verificationRecord).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
 /*
 * This method verifies a user's email using provided token.
+* A code is invalidated once it expires or after OTP_MAX_ATTEMPTS wrong guesses.
  */
 func VerifyEmail(c *gin.Context) {
 	var req models.VerifyEmail
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.Error(err)
+		c.Error(response.NewHTTPError(http.StatusBadRequest, "Invalid request", nil))
 		return
 	}
 
 	var verificationToken models.UserEmailVerification
-	result := initializers.DB.Where("user_id = ?", req.UserID).First(&verificationToken)
+	result := initializers.DB.Where("user_id = ?", req.UserID).Order("created_at desc").First(&verificationToken)
 	if result.Error == gorm.ErrRecordNotFound {
+		c.Error(response.NewHTTPError(http.StatusNotFound, "No pending verification code, please request a new one", nil))
+		return
+	} else if result.Error != nil {
 		c.Error(result.Error)
+		return
+	}
+
+	if verificationToken.IsExpired() {
+		initializers.DB.Delete(&verificationToken)
+		c.Error(response.NewHTTPError(http.StatusGone, "Verification code has expired, please request a new one", nil))
+		return
+	}
+
+	maxAttempts := initializers.GetEnvInt("OTP_MAX_ATTEMPTS", 5)
+
+	// Every guess claims an attempt before the code is compared, so parallel guesses cannot exceed the limit.
+	claim := initializers.DB.Model(&verificationToken).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "attempts"}}}).
+		Where("attempts < ?", maxAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if claim.Error != nil {
+		c.Error(claim.Error)
+		return
+	}
+	if claim.RowsAffected == 0 {
+		initializers.DB.Delete(&verificationToken)
+		c.Error(response.NewHTTPError(http.StatusTooManyRequests, "Too many incorrect attempts, please request a new code", nil))
+		return
 	}
 
-	if matched, err := models.CompareToken(verificationToken.Token, req.Token); err != nil || !matched {
-		if err == nil {
-			err = fmt.Errorf("token mismatch")
+	if matched, _ := models.CompareToken(verificationToken.Token, req.Token); !matched {
+		if verificationToken.Attempts >= maxAttempts {
+			initializers.DB.Delete(&verificationToken)
+			c.Error(response.NewHTTPError(http.StatusTooManyRequests, "Too many incorrect attempts, please request a new code", nil))
+			return
 		}
-		c.Error(err)
+
+		c.Error(response.NewHTTPError(http.StatusBadRequest, "Invalid verification code", gin.H{"attempts_left": maxAttempts - verificationToken.Attempts}))
 		return
 	}
 
@@ -102,7 +161,7 @@ func VerifyEmail(c *gin.Context) {
 		return
 	}
 
-	deleteResult := initializers.DB.Delete(&verificationToken)
+	deleteResult := initializers.DB.Where("user_id = ?", verificationToken.UserID).Delete(&models.UserEmailVerification{})
 	if deleteResult.Error != nil {
 		c.Error(deleteResult.Error)
 		return
@@ -113,32 +172,45 @@ func VerifyEmail(c *gin.Context) {
 
 /**
  * This method resends a verification token to the user's email.
+ * The new code replaces any older one and can only be requested once per OTP_RESEND_COOLDOWN.
  */
 func ReVerifyEmail(c *gin.Context) {
 	var req models.ReVerifyEmail
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.Error(err)
+		c.Error(response.NewHTTPError(http.StatusBadRequest, "Invalid request", nil))
 		return
 	}
 
 	var user models.User
 	result := initializers.DB.Where("id = ?", req.UserID).First(&user)
 	if result.Error == gorm.ErrRecordNotFound {
+		c.Error(response.NewHTTPError(http.StatusNotFound, "User not found", nil))
+		return
+	} else if result.Error != nil {
 		c.Error(result.Error)
 		return
 	}
 
-	token := utils.GenerateToken(6)
+	if user.Verified {
+		c.Error(response.NewHTTPError(http.StatusBadRequest, "Email is already verified", nil))
+		return
+	}
+
+	cooldown := initializers.GetEnvDuration("OTP_RESEND_COOLDOWN", time.Minute)
 
-	verificationRecord := models.UserEmailVerification{
-		UserID:    user.ID,
-		Token:     token,
-		CreatedAt: time.Now(),
+	var pending models.UserEmailVerification
+	if err := initializers.DB.Where("user_id = ?", user.ID).Order("created_at desc").First(&pending).Error; err == nil {
+		if wait := time.Until(pending.CreatedAt.Add(cooldown)); wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			c.Error(response.NewHTTPError(http.StatusTooManyRequests, "Please wait before requesting a new code", gin.H{"retry_after": int(wait.Seconds()) + 1}))
+			return
+		}
 	}
 
-	if result := initializers.DB.Create(&verificationRecord); result.Error != nil {
-		c.Error(result.Error)
+	token, err := issueEmailVerification(initializers.DB, user.ID)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -150,6 +222,7 @@ func ReVerifyEmail(c *gin.Context) {
 
 	if err := utils.SendVerificationMail(token, profile); err != nil {
 		log.Printf("Error sending verification mail: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
 		return
 	}
 
@@ -182,25 +255,14 @@ func Signin(c *gin.Context) {
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userId": user.ID,
-		"exp":    time.Now().Add(time.Hour * 72).Unix(),
-	})
-
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
-	if err != nil {
+	family := utils.GenerateRandomHexString(16)
+	if family == "" {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign the token"})
 		return
 	}
 
-	newToken := models.Token{
-		UserID:    user.ID,
-		Token:     tokenString,
-		Type:      "auth",
-		ExpiresAt: time.Now().Add(time.Hour * 72),
-	}
-
-	if err := initializers.DB.Create(&newToken).Error; err != nil {
+	pair, err := issueTokenPair(initializers.DB, user.ID, family, requestDevice(c), c.ClientIP())
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save new token"})
 		return
 	}
@@ -211,10 +273,13 @@ func Signin(c *gin.Context) {
 			"name":     user.Name,
 			"email":    user.Email,
 			"verified": user.Verified,
-			"avatar":   user.AvatarURL,
+			"avatar":   utils.FileURL(user.AvatarURL),
 			"is_admin": user.IsAdmin,
 		},
-		"token": tokenString,
+		"token":        pair.AccessToken,
+		"refreshToken": pair.RefreshToken,
+		"expiresIn":    pair.ExpiresIn,
+		"sessionId":    pair.SessionID,
 	})
 }
 
@@ -235,13 +300,24 @@ func Signout(c *gin.Context) {
 		return
 	}
 
-	tokenStr := c.PostForm("token")
-	if tokenStr == "" {
+	token, exists := c.Get("token")
+	if !exists {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Token not provided"})
 		return
 	}
 
-	err := initializers.DB.Where("user_id = ? AND token = ?", userModel.ID, tokenStr).Delete(&models.Token{}).Error
+	tokenModel, ok := token.(*models.Token)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token casting error"})
+		return
+	}
+
+	var err error
+	if tokenModel.Family != "" {
+		err = revokeSession(initializers.DB, userModel.ID, tokenModel.Family)
+	} else {
+		err = initializers.DB.Delete(tokenModel).Error
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign out"})
 		return
@@ -260,13 +336,15 @@ func SendProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"profile": user})
 }
 
+const passwordResetTTL = time.Hour
+
 /*
-* This method updates users password
+* This method changes the password of the authenticated user after checking the current one
  */
 func UpdatePassword(c *gin.Context) {
 	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		CurrentPassword string `json:"currentPassword"`
+		Password        string `json:"password"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -274,20 +352,35 @@ func UpdatePassword(c *gin.Context) {
 		return
 	}
 
-	var user models.User
-	result := initializers.DB.Where("email = ?", req.Email).First(&user)
-	if result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User casting error"})
+		return
+	}
+
+	if !models.CheckPasswordHash(req.CurrentPassword, userModel.Password) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid password"})
 		return
 	}
 
-	if models.CheckPasswordHash(req.Password, user.Password) {
+	if len(req.Password) < 8 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters long"})
+		return
+	}
+
+	if models.CheckPasswordHash(req.Password, userModel.Password) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "The new password must be different from the current one"})
 		return
 	}
 
-	user.Password = req.Password
-	if err := initializers.DB.Save(&user).Error; err != nil {
+	userModel.Password = req.Password
+	if err := initializers.DB.Save(userModel).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
 	}
@@ -295,6 +388,146 @@ func UpdatePassword(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
 }
 
+/*
+* This method issues a single-use password reset token and emails the reset link to the user.
+* The response is the same whether or not the email is registered.
+ */
+func ForgotPassword(c *gin.Context) {
+	var req models.ForgotPassword
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if validationErr := Validate.Struct(req); validationErr != nil {
+		c.Error(validationErr)
+		return
+	}
+
+	message := gin.H{"message": "If the email is registered, a reset link has been sent"}
+
+	var user models.User
+	if err := initializers.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusOK, message)
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	token := utils.GenerateRandomHexString(32)
+	if token == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate reset token"})
+		return
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.UserPasswordReset{}).Error; err != nil {
+			return err
+		}
+
+		resetRecord := models.UserPasswordReset{
+			UserID:    user.ID,
+			Token:     token,
+			CreatedAt: time.Now(),
+			ExpiresAt: time.Now().Add(passwordResetTTL),
+		}
+		return tx.Create(&resetRecord).Error
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	link := fmt.Sprintf("%s/reset-password?id=%d&token=%s", frontendURL(), user.ID, token)
+	profile := utils.Profile{Name: user.Name, Email: user.Email, UserID: fmt.Sprintf("%d", user.ID)}
+	if err := utils.SendPasswordResetMail(link, profile); err != nil {
+		log.Printf("Error sending password reset mail: %v", err)
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+/*
+* This method verifies a password reset token, sets the new password and signs the user out everywhere
+ */
+func ResetPassword(c *gin.Context) {
+	var req models.ResetPassword
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if validationErr := Validate.Struct(req); validationErr != nil {
+		c.Error(validationErr)
+		return
+	}
+
+	var resetRecord models.UserPasswordReset
+	if err := initializers.DB.Where("user_id = ?", req.UserID).Order("created_at desc").First(&resetRecord).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	if resetRecord.IsExpired() {
+		initializers.DB.Where("user_id = ?", req.UserID).Delete(&models.UserPasswordReset{})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	if matched, _ := models.CompareToken(resetRecord.Token, req.Token); !matched {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	var user models.User
+	if err := initializers.DB.First(&user, req.UserID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// Claim the token before touching the password so concurrent requests with the same code cannot both succeed.
+		result := tx.Where("reset_id = ? AND user_id = ?", resetRecord.ResetID, user.ID).Delete(&models.UserPasswordReset{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errResetTokenUsed
+		}
+
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.UserPasswordReset{}).Error; err != nil {
+			return err
+		}
+
+		user.Password = req.Password
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", user.ID).Delete(&models.Token{}).Error
+	})
+	if errors.Is(err, errResetTokenUsed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset, please sign in again"})
+}
+
+func frontendURL() string {
+	if url := os.Getenv("FRONTEND_URL"); url != "" {
+		return url
+	}
+	return "https://audify-frontend-2ce95bcaa3fa.herokuapp.com"
+}
+
 /*
 * This method handles image uploading and saving in cloud, and also updating profile
  */
@@ -328,28 +561,20 @@ func UpdateProfile(c *gin.Context) {
 	file, fileErr := c.FormFile("picFile")
 	if fileErr == nil && file != nil {
 		if userModel.AvatarPublicID != "" {
-			if err := utils.DestroyImage(userModel.AvatarPublicID); err != nil {
+			if err := utils.DeleteFile(c.Request.Context(), userModel.AvatarPublicID); err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to destroy existing image"})
 				return
 			}
 		}
 
-		filepath := file.Filename
-		picFile, err := file.Open()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
-			return
-		}
-		defer picFile.Close()
-
-		imageURL, publicID, err := utils.UploadToCloudinary(picFile, filepath)
+		avatarInfo, err := utils.UploadFile(c.Request.Context(), file, "avatars")
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file"})
 			return
 		}
 
-		updateData["avatar_url"] = imageURL
-		updateData["avatar_public_id"] = publicID
+		updateData["avatar_url"] = avatarInfo.URL
+		updateData["avatar_public_id"] = avatarInfo.Key
 	} else if fileErr != http.ErrMissingFile {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Error retrieving file"})
 		return
@@ -381,7 +606,7 @@ func GetRecommendedUsers(c *gin.Context) {
 
 	var users []models.User
 	result := initializers.DB.
-		Joins("JOIN audios ON audios.owner = users.id").
+		Joins("JOIN audios ON audios.owner = users.id AND audios.visibility = ?", models.AudioPublic).
 		Where("users.id != ?", userModel.ID).
 		Group("users.id").
 		Having("count(audios.id) >= ?", 1).
@@ -397,7 +622,7 @@ func GetRecommendedUsers(c *gin.Context) {
 		userProfiles[i] = map[string]interface{}{
 			"id":     user.ID,
 			"name":   user.Name,
-			"avatar": user.AvatarURL,
+			"avatar": utils.FileURL(user.AvatarURL),
 		}
 	}
 
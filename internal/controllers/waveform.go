@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"backend/internal/initializers"
+	"backend/internal/media"
+	"backend/internal/models"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// waveformResolutions are the bucket counts generated for every audio.
+var waveformResolutions = []int{128, 512, 2048}
+
+const defaultWaveformResolution = 512
+
+// waveformStaleAfter is how long a waveform may stay pending before it is assumed lost, e.g. to a restart.
+// It is longer than the generation timeout.
+const waveformStaleAfter = 15 * time.Minute
+
+// waveformJobs tracks audios whose waveform is being generated in this process.
+var waveformJobs sync.Map
+
+// waveformSlots caps how many waveforms are decoded at the same time.
+var waveformSlots = make(chan struct{}, waveformConcurrency())
+
+/*
+* waveformConcurrency reads WAVEFORM_CONCURRENCY; values below one would block every job, so at least one slot is kept
+ */
+func waveformConcurrency() int {
+	if n := initializers.GetEnvInt("WAVEFORM_CONCURRENCY", 2); n > 0 {
+		return n
+	}
+	return 1
+}
+
+/*
+* generateWaveformAsync computes the waveform of a new upload in the background, waiting for a free slot
+ */
+func generateWaveformAsync(audioID uint, key string) {
+	startWaveform(audioID, key, true)
+}
+
+/*
+* startWaveform queues the waveform generation of an audio. Without wait it only starts when a slot is free
+* and reports whether it did, so requests cannot pile up decode jobs.
+ */
+func startWaveform(audioID uint, key string, wait bool) bool {
+	if !wait {
+		select {
+		case waveformSlots <- struct{}{}:
+		default:
+			return false
+		}
+	}
+
+	if _, running := waveformJobs.LoadOrStore(audioID, struct{}{}); running {
+		if !wait {
+			<-waveformSlots
+		}
+		return true
+	}
+
+	initializers.DB.Model(&models.Audio{}).Where("id = ?", audioID).UpdateColumns(map[string]interface{}{
+		"waveform_status":    models.WaveformPending,
+		"waveform_queued_at": time.Now(),
+	})
+
+	go func() {
+		defer waveformJobs.Delete(audioID)
+		if wait {
+			waveformSlots <- struct{}{}
+		}
+		defer func() { <-waveformSlots }()
+
+		status := models.WaveformReady
+		if err := generateWaveform(audioID, key); err != nil {
+			log.Printf("Failed to generate waveform of audio %d: %v", audioID, err)
+			status = models.WaveformUnavailable
+		}
+
+		initializers.DB.Model(&models.Audio{}).Where("id = ?", audioID).UpdateColumn("waveform_status", status)
+	}()
+	return true
+}
+
+/*
+* generateWaveform decodes the stored audio file and saves its peaks at every resolution
+ */
+func generateWaveform(audioID uint, key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	object, err := initializers.Storage.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	format, err := media.DetectFormat(object)
+	if err != nil {
+		return err
+	}
+
+	peaks, err := media.ComputePeaks(ctx, object, format, waveformResolutions)
+	if err != nil {
+		return err
+	}
+
+	return initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("audio_id = ?", audioID).Delete(&models.Waveform{}).Error; err != nil {
+			return err
+		}
+
+		for _, p := range peaks {
+			data := make([]byte, len(p.Data))
+			for i, v := range p.Data {
+				data[i] = byte(v)
+			}
+
+			if err := tx.Create(&models.Waveform{AudioID: audioID, Resolution: p.Resolution, Peaks: data}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+/*
+* GetWaveform returns the peaks of an audio for drawing a seek bar.
+* Supports 'resolution' (number of buckets) and 'format' ('json' or 'binary') query parameters.
+* Responds with 202 while the waveform is still being generated. Private audios need their owner or a share link.
+ */
+func GetWaveform(c *gin.Context) {
+	audio, ok := findViewableAudio(c, c.Param("audioId"))
+	if !ok {
+		return
+	}
+
+	switch audio.WaveformStatus {
+	case models.WaveformReady:
+	case models.WaveformUnavailable:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Waveform not available for this audio"})
+		return
+	default:
+		if audio.AudioPublicID == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Waveform not available for this audio"})
+			return
+		}
+		// Audio uploaded before waveforms existed is processed on first request, and so is
+		// audio whose generation was lost. When every slot is busy a later request retries.
+		stale := audio.WaveformQueuedAt == nil || time.Since(*audio.WaveformQueuedAt) > waveformStaleAfter
+		if audio.WaveformStatus == "" || (audio.WaveformStatus == models.WaveformPending && stale) {
+			startWaveform(audio.ID, audio.AudioPublicID, false)
+		}
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusAccepted, gin.H{"message": "Waveform is being generated"})
+		return
+	}
+
+	resolution, err := strconv.Atoi(c.DefaultQuery("resolution", strconv.Itoa(defaultWaveformResolution)))
+	if err != nil || resolution < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resolution"})
+		return
+	}
+
+	// Pick the smallest stored resolution that is at least as detailed as requested.
+	var waveform models.Waveform
+	err = initializers.DB.Where("audio_id = ? AND resolution >= ?", audio.ID, resolution).Order("resolution asc").First(&waveform).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = initializers.DB.Where("audio_id = ?", audio.ID).Order("resolution desc").First(&waveform).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Waveform not available for this audio"})
+		return
+	}
+
+	if audio.Visibility == models.AudioPublic {
+		c.Header("Cache-Control", "public, max-age=86400")
+	} else {
+		c.Header("Cache-Control", "private, max-age=86400")
+	}
+	c.Header("X-Waveform-Resolution", strconv.Itoa(waveform.Resolution))
+
+	if c.Query("format") == "binary" {
+		c.Data(http.StatusOK, "application/octet-stream", waveform.Peaks)
+		return
+	}
+
+	data := make([]int8, len(waveform.Peaks))
+	for i, v := range waveform.Peaks {
+		data[i] = int8(v)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"audio_id":   audio.ID,
+		"resolution": waveform.Resolution,
+		"duration":   audio.Duration,
+		"bits":       8,
+		"data":       data,
+	})
+}
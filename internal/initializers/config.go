@@ -0,0 +1,29 @@
+package initializers
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+/*
+* GetEnvInt reads an integer environment variable, falling back to the default when unset or invalid
+ */
+func GetEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+/*
+* GetEnvDuration reads a duration such as "10m" or "90s" from the environment, falling back to the default when unset or invalid
+ */
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
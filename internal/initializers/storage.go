@@ -0,0 +1,54 @@
+package initializers
+
+import (
+	"backend/internal/storage"
+	"errors"
+	"log"
+	"os"
+)
+
+var Storage storage.Storage
+
+/*
+* Sets up the file storage backend selected by STORAGE_BACKEND (cloudinary, local or s3)
+ */
+func SetupStorage() {
+	backend, err := newStorage(os.Getenv("STORAGE_BACKEND"))
+	if err != nil {
+		log.Fatalf("Failed to set up storage: %v", err)
+	}
+
+	Storage = backend
+}
+
+func newStorage(kind string) (storage.Storage, error) {
+	switch kind {
+	case "local":
+		root := os.Getenv("STORAGE_LOCAL_DIR")
+		if root == "" {
+			root = "./uploads"
+		}
+		// File links get their own key so they do not share the fate of the JWT secret.
+		secret := os.Getenv("STORAGE_SIGNING_SECRET")
+		if secret == "" {
+			return nil, errors.New("STORAGE_SIGNING_SECRET is required for local storage")
+		}
+		return storage.NewLocalStorage(root, os.Getenv("STORAGE_BASE_URL"), secret), nil
+	case "s3":
+		return storage.NewS3Storage(
+			os.Getenv("S3_ENDPOINT"),
+			os.Getenv("S3_ACCESS_KEY"),
+			os.Getenv("S3_SECRET_KEY"),
+			os.Getenv("S3_BUCKET"),
+			os.Getenv("S3_REGION"),
+			os.Getenv("S3_USE_SSL") != "false",
+			os.Getenv("S3_PUBLIC_URL"),
+		)
+	default:
+		cld, err := SetupCloudinary()
+		if err != nil {
+			return nil, err
+		}
+		return storage.NewCloudinaryStorage(cld), nil
+	}
+}
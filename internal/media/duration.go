@@ -0,0 +1,214 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/hajimehoshi/go-mp3"
+)
+
+var errNoDuration = errors.New("media: duration not found")
+
+/*
+* Duration returns the playing time of the stream in seconds
+ */
+func Duration(r io.ReadSeeker, format string) (float64, error) {
+	switch format {
+	case FormatMP3:
+		return mp3Duration(r)
+	case FormatWAV:
+		return wavDuration(r)
+	case FormatFLAC:
+		return flacDuration(r)
+	case FormatOgg:
+		return oggDuration(r)
+	case FormatMP4:
+		return mp4Duration(r)
+	}
+	return 0, ErrUnknownFormat
+}
+
+func mp3Duration(r io.ReadSeeker) (float64, error) {
+	decoder, err := mp3.NewDecoder(r)
+	if err != nil {
+		return 0, err
+	}
+
+	// Length is the size of the decoded 16-bit stereo PCM stream in bytes.
+	if decoder.Length() <= 0 || decoder.SampleRate() == 0 {
+		return 0, errNoDuration
+	}
+	return float64(decoder.Length()) / 4 / float64(decoder.SampleRate()), nil
+}
+
+func wavDuration(r io.ReadSeeker) (float64, error) {
+	format, dataSize, _, err := readWAVHeader(r)
+	if err != nil {
+		return 0, err
+	}
+	if format.ByteRate == 0 {
+		return 0, errNoDuration
+	}
+	return float64(dataSize) / float64(format.ByteRate), nil
+}
+
+func flacDuration(r io.ReadSeeker) (float64, error) {
+	if _, err := skipID3v2(r); err != nil {
+		return 0, err
+	}
+
+	// "fLaC", a 4 byte metadata block header, then the 34 byte STREAMINFO block.
+	buf := make([]byte, 42)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+	if !bytes.HasPrefix(buf, []byte("fLaC")) || buf[4]&0x7F != 0 {
+		return 0, errNoDuration
+	}
+
+	info := buf[8:]
+	sampleRate := uint64(info[10])<<12 | uint64(info[11])<<4 | uint64(info[12])>>4
+	totalSamples := uint64(info[13]&0x0F)<<32 | uint64(binary.BigEndian.Uint32(info[14:18]))
+	if sampleRate == 0 || totalSamples == 0 {
+		return 0, errNoDuration
+	}
+	return float64(totalSamples) / float64(sampleRate), nil
+}
+
+/*
+* oggDuration reads the sample rate from the Vorbis or Opus identification header and
+* the granule position of the last page
+ */
+func oggDuration(r io.ReadSeeker) (float64, error) {
+	head := make([]byte, 128)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, err
+	}
+	head = head[:n]
+
+	var sampleRate float64
+	var preSkip int64
+	if i := bytes.Index(head, []byte("\x01vorbis")); i >= 0 && len(head) >= i+16 {
+		sampleRate = float64(binary.LittleEndian.Uint32(head[i+12 : i+16]))
+	} else if i := bytes.Index(head, []byte("OpusHead")); i >= 0 && len(head) >= i+12 {
+		// Opus granule positions always count 48 kHz samples.
+		sampleRate = 48000
+		preSkip = int64(binary.LittleEndian.Uint16(head[i+10 : i+12]))
+	}
+	if sampleRate == 0 {
+		return 0, errNoDuration
+	}
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	tailSize := int64(64 * 1024)
+	if tailSize > size {
+		tailSize = size
+	}
+	if _, err := r.Seek(size-tailSize, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	tail := make([]byte, tailSize)
+	if _, err := io.ReadFull(r, tail); err != nil {
+		return 0, err
+	}
+
+	i := bytes.LastIndex(tail, []byte("OggS"))
+	if i < 0 || len(tail) < i+14 {
+		return 0, errNoDuration
+	}
+
+	granule := int64(binary.LittleEndian.Uint64(tail[i+6 : i+14]))
+	if granule <= preSkip {
+		return 0, errNoDuration
+	}
+	return float64(granule-preSkip) / sampleRate, nil
+}
+
+/*
+* mp4Duration walks the box tree down to moov/mvhd and reads the movie timescale and duration
+ */
+func mp4Duration(r io.ReadSeeker) (float64, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	moovStart, moovSize, err := findBox(r, 0, size, "moov")
+	if err != nil {
+		return 0, err
+	}
+
+	mvhdStart, _, err := findBox(r, moovStart, moovStart+moovSize, "mvhd")
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := r.Seek(mvhdStart, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	// version(1) flags(3), then creation/modification times, timescale and duration
+	// as 32-bit fields for version 0 or 64-bit times and duration for version 1.
+	buf := make([]byte, 32)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+
+	var timescale, duration uint64
+	if buf[0] == 1 {
+		timescale = uint64(binary.BigEndian.Uint32(buf[20:24]))
+		duration = binary.BigEndian.Uint64(buf[24:32])
+	} else {
+		timescale = uint64(binary.BigEndian.Uint32(buf[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(buf[16:20]))
+	}
+	if timescale == 0 {
+		return 0, errNoDuration
+	}
+	return float64(duration) / float64(timescale), nil
+}
+
+/*
+* findBox scans the boxes between start and end and returns the payload offset and size of the first one of the given type
+ */
+func findBox(r io.ReadSeeker, start, end int64, boxType string) (int64, int64, error) {
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return 0, 0, err
+		}
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			return 0, 0, err
+		}
+
+		boxSize := int64(binary.BigEndian.Uint32(header[0:4]))
+		headerSize := int64(8)
+		switch boxSize {
+		case 0:
+			boxSize = end - offset
+		case 1:
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return 0, 0, err
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if boxSize < headerSize {
+			return 0, 0, errNoDuration
+		}
+
+		if string(header[4:8]) == boxType {
+			return offset + headerSize, boxSize - headerSize, nil
+		}
+		offset += boxSize
+	}
+	return 0, 0, errNoDuration
+}
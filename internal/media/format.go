@@ -0,0 +1,77 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+const (
+	FormatMP3  = "mp3"
+	FormatFLAC = "flac"
+	FormatOgg  = "ogg"
+	FormatWAV  = "wav"
+	FormatMP4  = "mp4"
+)
+
+// ErrUnknownFormat is returned for streams that are not a supported audio container.
+var ErrUnknownFormat = errors.New("media: unknown audio format")
+
+/*
+* DetectFormat identifies the audio container from the leading bytes of the stream
+ */
+func DetectFormat(r io.ReadSeeker) (string, error) {
+	offset, err := skipID3v2(r)
+	if err != nil {
+		return "", err
+	}
+
+	header := make([]byte, 12)
+	n, err := io.ReadFull(r, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return FormatFLAC, nil
+	case bytes.HasPrefix(header, []byte("OggS")):
+		return FormatOgg, nil
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WAVE")):
+		return FormatWAV, nil
+	case len(header) >= 8 && bytes.Equal(header[4:8], []byte("ftyp")):
+		return FormatMP4, nil
+	case len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0 && header[1]&0x06 != 0:
+		return FormatMP3, nil
+	case offset > 0:
+		// An ID3v2 tag followed by something other than the above is still most likely MP3.
+		return FormatMP3, nil
+	}
+
+	return "", ErrUnknownFormat
+}
+
+/*
+* skipID3v2 positions the reader after a leading ID3v2 tag, if any, and returns the new offset
+ */
+func skipID3v2(r io.ReadSeeker) (int64, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.HasPrefix(header, []byte("ID3")) {
+		_, seekErr := r.Seek(0, io.SeekStart)
+		return 0, seekErr
+	}
+
+	// The tag size is a 28-bit syncsafe integer that excludes the 10 byte header.
+	size := int64(header[6]&0x7F)<<21 | int64(header[7]&0x7F)<<14 | int64(header[8]&0x7F)<<7 | int64(header[9]&0x7F)
+	offset := 10 + size
+	if header[5]&0x10 != 0 {
+		offset += 10 // footer
+	}
+
+	return r.Seek(offset, io.SeekStart)
+}
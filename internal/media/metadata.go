@@ -0,0 +1,72 @@
+package media
+
+import (
+	"io"
+	"strings"
+
+	"github.com/dhowden/tag"
+)
+
+// Metadata is what can be read from an uploaded audio file. Zero values mean
+// the information was not present.
+type Metadata struct {
+	Format      string
+	Title       string
+	Artist      string
+	Album       string
+	TrackNumber int
+	Year        int
+	Genre       string
+	Duration    float64 // seconds
+	Picture     *Picture
+}
+
+// Picture is embedded cover art.
+type Picture struct {
+	MIMEType string
+	Ext      string
+	Data     []byte
+}
+
+/*
+* ExtractMetadata reads ID3, Vorbis comment and MP4 tags together with the duration of the stream.
+* Missing tags are not an error; only a failure to read the stream is.
+ */
+func ExtractMetadata(r io.ReadSeeker) (*Metadata, error) {
+	format, err := DetectFormat(r)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &Metadata{Format: format}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if tags, err := tag.ReadFrom(r); err == nil {
+		meta.Title = strings.TrimSpace(tags.Title())
+		meta.Artist = strings.TrimSpace(tags.Artist())
+		if meta.Artist == "" {
+			meta.Artist = strings.TrimSpace(tags.AlbumArtist())
+		}
+		meta.Album = strings.TrimSpace(tags.Album())
+		meta.TrackNumber, _ = tags.Track()
+		meta.Year = tags.Year()
+		meta.Genre = strings.TrimSpace(tags.Genre())
+
+		if picture := tags.Picture(); picture != nil && len(picture.Data) > 0 {
+			meta.Picture = &Picture{MIMEType: picture.MIMEType, Ext: picture.Ext, Data: picture.Data}
+		}
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if duration, err := Duration(r, format); err == nil {
+		meta.Duration = duration
+	}
+
+	return meta, nil
+}
@@ -0,0 +1,343 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// wavBytes builds a 16-bit PCM WAV file from interleaved samples.
+func wavBytes(channels, sampleRate int, samples []int16) []byte {
+	data := new(bytes.Buffer)
+	binary.Write(data, binary.LittleEndian, samples)
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, uint32(36+data.Len()))
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	binary.Write(buf, binary.LittleEndian, uint32(16))
+	binary.Write(buf, binary.LittleEndian, wavFormat{
+		AudioFormat:   1,
+		Channels:      uint16(channels),
+		SampleRate:    uint32(sampleRate),
+		ByteRate:      uint32(sampleRate * channels * 2),
+		BlockAlign:    uint16(channels * 2),
+		BitsPerSample: 16,
+	})
+
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, uint32(data.Len()))
+	buf.Write(data.Bytes())
+	return buf.Bytes()
+}
+
+// flacBytes builds the FLAC signature and a STREAMINFO block.
+func flacBytes(sampleRate uint32, totalSamples uint64) []byte {
+	info := make([]byte, 34)
+	info[10] = byte(sampleRate >> 12)
+	info[11] = byte(sampleRate >> 4)
+	info[12] = byte(sampleRate<<4) | 0x02 // channels and bits per sample share the low bits
+	info[13] = byte(totalSamples>>32) & 0x0F
+	binary.BigEndian.PutUint32(info[14:18], uint32(totalSamples))
+
+	buf := []byte("fLaC")
+	buf = append(buf, 0x80, 0, 0, 34) // last block, STREAMINFO, 34 bytes
+	return append(buf, info...)
+}
+
+// oggPage builds an Ogg page header with the given granule position followed by the payload.
+func oggPage(granule int64, payload []byte) []byte {
+	header := make([]byte, 27)
+	copy(header, "OggS")
+	binary.LittleEndian.PutUint64(header[6:14], uint64(granule))
+	return append(header, payload...)
+}
+
+func vorbisBytes(sampleRate uint32, lastGranule int64) []byte {
+	ident := make([]byte, 16)
+	copy(ident, "\x01vorbis")
+	binary.LittleEndian.PutUint32(ident[12:16], sampleRate)
+	return append(oggPage(0, ident), oggPage(lastGranule, nil)...)
+}
+
+func opusBytes(preSkip uint16, lastGranule int64) []byte {
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	binary.LittleEndian.PutUint16(head[10:12], preSkip)
+	return append(oggPage(0, head), oggPage(lastGranule, nil)...)
+}
+
+func mp4Box(boxType string, payload []byte) []byte {
+	box := make([]byte, 8)
+	binary.BigEndian.PutUint32(box[0:4], uint32(8+len(payload)))
+	copy(box[4:8], boxType)
+	return append(box, payload...)
+}
+
+func mp4Bytes(version byte, timescale uint32, duration uint64) []byte {
+	var mvhd []byte
+	if version == 1 {
+		mvhd = make([]byte, 112)
+		binary.BigEndian.PutUint32(mvhd[20:24], timescale)
+		binary.BigEndian.PutUint64(mvhd[24:32], duration)
+	} else {
+		mvhd = make([]byte, 100)
+		binary.BigEndian.PutUint32(mvhd[12:16], timescale)
+		binary.BigEndian.PutUint32(mvhd[16:20], uint32(duration))
+	}
+	mvhd[0] = version
+
+	ftyp := mp4Box("ftyp", []byte("M4A \x00\x00\x00\x00"))
+	moov := mp4Box("moov", append(mp4Box("udta", nil), mp4Box("mvhd", mvhd)...))
+	return append(ftyp, moov...)
+}
+
+// id3Bytes prefixes the data with an empty ID3v2 tag of the given size.
+func id3Bytes(size int, data []byte) []byte {
+	header := []byte{'I', 'D', '3', 4, 0, 0,
+		byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}
+	return append(append(header, make([]byte, size)...), data...)
+}
+
+// id3v23Frame builds an ID3v2.3 frame, whose size is a plain big-endian integer.
+func id3v23Frame(id string, payload []byte) []byte {
+	frame := make([]byte, 10)
+	copy(frame, id)
+	binary.BigEndian.PutUint32(frame[4:8], uint32(len(payload)))
+	return append(frame, payload...)
+}
+
+// id3v23Tag wraps the frames in an ID3v2.3 tag followed by the data.
+func id3v23Tag(frames [][]byte, data []byte) []byte {
+	body := bytes.Join(frames, nil)
+	size := len(body)
+	header := []byte{'I', 'D', '3', 3, 0, 0,
+		byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}
+	return append(append(header, body...), data...)
+}
+
+// id3Text builds the payload of an ISO-8859-1 text frame.
+func id3Text(text string) []byte {
+	return append([]byte{0}, text...)
+}
+
+// mp4Item builds an ilst item holding a single data atom of the given class.
+func mp4Item(name string, class byte, value []byte) []byte {
+	payload := []byte{0, 0, 0, class, 0, 0, 0, 0}
+	return mp4Box(name, mp4Box("data", append(payload, value...)))
+}
+
+// mp4TaggedBytes builds an MP4 file whose moov carries an iTunes ilst next to the mvhd.
+func mp4TaggedBytes(items [][]byte, timescale, duration uint32) []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:16], timescale)
+	binary.BigEndian.PutUint32(mvhd[16:20], duration)
+
+	meta := mp4Box("meta", append(make([]byte, 4), mp4Box("ilst", bytes.Join(items, nil))...))
+	ftyp := mp4Box("ftyp", []byte("M4A \x00\x00\x00\x00"))
+	moov := mp4Box("moov", append(mp4Box("mvhd", mvhd), mp4Box("udta", meta)...))
+	return append(ftyp, moov...)
+}
+
+func TestDetectFormat(t *testing.T) {
+	mp3Frame := []byte{0xFF, 0xFB, 0x90, 0x64, 0, 0, 0, 0, 0, 0, 0, 0}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		format string
+		err    error
+	}{
+		{"wav", wavBytes(1, 8000, make([]int16, 8)), FormatWAV, nil},
+		{"flac", flacBytes(44100, 44100), FormatFLAC, nil},
+		{"ogg", vorbisBytes(44100, 44100), FormatOgg, nil},
+		{"mp4", mp4Bytes(0, 1000, 1000), FormatMP4, nil},
+		{"mp3 frame", mp3Frame, FormatMP3, nil},
+		{"id3 then mp3 frame", id3Bytes(32, mp3Frame), FormatMP3, nil},
+		{"id3 then flac", id3Bytes(32, flacBytes(44100, 44100)), FormatFLAC, nil},
+		{"id3 then unknown data", id3Bytes(32, []byte("garbage data")), FormatMP3, nil},
+		{"unknown", []byte("definitely not audio"), "", ErrUnknownFormat},
+		{"too short", []byte("RI"), "", ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := DetectFormat(bytes.NewReader(tt.data))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("DetectFormat() error = %v, want %v", err, tt.err)
+			}
+			if format != tt.format {
+				t.Errorf("DetectFormat() = %q, want %q", format, tt.format)
+			}
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		format   string
+		duration float64
+		err      error
+	}{
+		{"wav mono", wavBytes(1, 8000, make([]int16, 16000)), FormatWAV, 2, nil},
+		{"wav stereo", wavBytes(2, 8000, make([]int16, 8000)), FormatWAV, 0.5, nil},
+		{"flac", flacBytes(44100, 441000), FormatFLAC, 10, nil},
+		{"flac after id3", id3Bytes(16, flacBytes(48000, 24000)), FormatFLAC, 0.5, nil},
+		{"flac without samples", flacBytes(44100, 0), FormatFLAC, 0, errNoDuration},
+		{"vorbis", vorbisBytes(44100, 44100*3), FormatOgg, 3, nil},
+		{"opus pre-skip", opusBytes(312, 48000+312), FormatOgg, 1, nil},
+		{"ogg without codec header", oggPage(48000, []byte("unknown codec")), FormatOgg, 0, errNoDuration},
+		{"mp4 version 0", mp4Bytes(0, 1000, 61500), FormatMP4, 61.5, nil},
+		{"mp4 version 1", mp4Bytes(1, 44100, 44100*4), FormatMP4, 4, nil},
+		{"mp4 without moov", mp4Box("ftyp", []byte("M4A ")), FormatMP4, 0, errNoDuration},
+		{"unknown format", []byte("data"), "aiff", 0, ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			duration, err := Duration(bytes.NewReader(tt.data), tt.format)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Duration() error = %v, want %v", err, tt.err)
+			}
+			if math.Abs(duration-tt.duration) > 1e-9 {
+				t.Errorf("Duration() = %v, want %v", duration, tt.duration)
+			}
+		})
+	}
+}
+
+func TestExtractMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		format   string
+		duration float64
+		err      error
+	}{
+		{"untagged wav", wavBytes(2, 44100, make([]int16, 44100*2)), FormatWAV, 1, nil},
+		{"untagged flac", flacBytes(44100, 44100*5), FormatFLAC, 5, nil},
+		{"unknown format", []byte("not audio at all"), "", 0, ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, err := ExtractMetadata(bytes.NewReader(tt.data))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ExtractMetadata() error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if meta.Format != tt.format {
+				t.Errorf("Format = %q, want %q", meta.Format, tt.format)
+			}
+			if math.Abs(meta.Duration-tt.duration) > 1e-9 {
+				t.Errorf("Duration = %v, want %v", meta.Duration, tt.duration)
+			}
+			if meta.Title != "" || meta.Picture != nil {
+				t.Errorf("untagged file yielded tags: %+v", meta)
+			}
+		})
+	}
+}
+
+func TestExtractMetadataTags(t *testing.T) {
+	cover := pngBytes(2, 2, color.White)
+	mp3Frame := []byte{0xFF, 0xFB, 0x90, 0x64, 0, 0, 0, 0, 0, 0, 0, 0}
+
+	apic := append([]byte{0}, "image/png\x00"...)
+	apic = append(apic, 3, 0) // front cover, empty description
+	apic = append(apic, cover...)
+
+	id3 := id3v23Tag([][]byte{
+		id3v23Frame("TIT2", id3Text("Night Drive")),
+		id3v23Frame("TPE1", id3Text("The Examples")),
+		id3v23Frame("TALB", id3Text("Test Pressing")),
+		id3v23Frame("TRCK", id3Text("3/12")),
+		id3v23Frame("TYER", id3Text("1999")),
+		id3v23Frame("TCON", id3Text("Synthwave")),
+		id3v23Frame("APIC", apic),
+	}, mp3Frame)
+
+	albumArtistOnly := id3v23Tag([][]byte{
+		id3v23Frame("TIT2", id3Text("Night Drive")),
+		id3v23Frame("TPE2", id3Text("Various Examples")),
+	}, mp3Frame)
+
+	m4a := mp4TaggedBytes([][]byte{
+		mp4Item("\xa9nam", 1, []byte("Night Drive")),
+		mp4Item("\xa9ART", 1, []byte("The Examples")),
+		mp4Item("\xa9alb", 1, []byte("Test Pressing")),
+		mp4Item("\xa9day", 1, []byte("1999-05-01")),
+		mp4Item("\xa9gen", 1, []byte("Synthwave")),
+		mp4Item("trkn", 0, []byte{0, 0, 0, 3, 0, 12, 0, 0}),
+		mp4Item("covr", 14, cover),
+	}, 1000, 90500)
+
+	tagged := Metadata{
+		Title:       "Night Drive",
+		Artist:      "The Examples",
+		Album:       "Test Pressing",
+		TrackNumber: 3,
+		Year:        1999,
+		Genre:       "Synthwave",
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		want     Metadata
+		picture  bool
+		duration float64
+	}{
+		{"id3v2.3 with apic", id3, withFormat(tagged, FormatMP3), true, 0},
+		{"id3 album artist fallback", albumArtistOnly, Metadata{Format: FormatMP3, Title: "Night Drive", Artist: "Various Examples"}, false, 0},
+		{"mp4 ilst with covr", m4a, withFormat(tagged, FormatMP4), true, 90.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, err := ExtractMetadata(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("ExtractMetadata() error = %v", err)
+			}
+
+			got := *meta
+			got.Picture, got.Duration = nil, 0
+			if got != tt.want {
+				t.Errorf("ExtractMetadata() = %+v, want %+v", got, tt.want)
+			}
+			if tt.duration != 0 && math.Abs(meta.Duration-tt.duration) > 1e-9 {
+				t.Errorf("Duration = %v, want %v", meta.Duration, tt.duration)
+			}
+
+			if !tt.picture {
+				if meta.Picture != nil {
+					t.Errorf("Picture = %+v, want nil", meta.Picture)
+				}
+				return
+			}
+			if meta.Picture == nil {
+				t.Fatal("Picture = nil, want embedded cover")
+			}
+			if meta.Picture.MIMEType != "image/png" || meta.Picture.Ext != "png" {
+				t.Errorf("Picture type = %q/%q, want image/png/png", meta.Picture.MIMEType, meta.Picture.Ext)
+			}
+			if !bytes.Equal(meta.Picture.Data, cover) {
+				t.Errorf("Picture.Data differs from the embedded cover (%d bytes, want %d)", len(meta.Picture.Data), len(cover))
+			}
+		})
+	}
+}
+
+func withFormat(meta Metadata, format string) Metadata {
+	meta.Format = format
+	return meta
+}
@@ -0,0 +1,118 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+const (
+	// MosaicSize is the width and height in pixels of generated playlist covers.
+	MosaicSize = 600
+
+	// MaxImagePixels bounds the images DecodeImage accepts. A small file can declare
+	// huge dimensions, and decoding allocates memory for every pixel.
+	MaxImagePixels = 40_000_000
+)
+
+var ErrImageTooLarge = errors.New("image dimensions are too large")
+
+/*
+* DecodeImage decodes a JPEG, PNG or GIF image after checking from its header that it is not larger than MaxImagePixels
+ */
+func DecodeImage(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > MaxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+/*
+* Mosaic arranges up to four images in a 2x2 grid of the given size. Every image is center-cropped to a square.
+* A single image fills the whole cover; with two or three the images repeat to complete the grid.
+* The grid is drawn here rather than with github.com/ozankasikci/go-image-merge, whose v0.3.0 module
+* only has internal packages and cannot be imported.
+ */
+func Mosaic(images []image.Image, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	if len(images) == 0 {
+		return dst
+	}
+	if len(images) == 1 {
+		draw.Draw(dst, dst.Bounds(), scaleSquare(images[0], size), image.Point{}, draw.Src)
+		return dst
+	}
+
+	tile := size / 2
+	for i := 0; i < 4; i++ {
+		// With two images they land on a diagonal rather than side by side.
+		src := images[i%len(images)]
+		if len(images) == 2 && i >= 2 {
+			src = images[(i+1)%2]
+		}
+		at := image.Pt((i%2)*tile, (i/2)*tile)
+		draw.Draw(dst, image.Rectangle{Min: at, Max: at.Add(image.Pt(tile, tile))}, scaleSquare(src, tile), image.Point{}, draw.Src)
+	}
+	return dst
+}
+
+// scaleSquare center-crops src to a square and resamples it to size x size by
+// averaging the source pixels that fall into every destination pixel.
+func scaleSquare(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	origin := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	if side == 0 {
+		return dst
+	}
+
+	for y := 0; y < size; y++ {
+		y0 := y * side / size
+		y1 := (y + 1) * side / size
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < size; x++ {
+			x0 := x * side / size
+			x1 := (x + 1) * side / size
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(origin.X+sx, origin.Y+sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
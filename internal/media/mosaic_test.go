@@ -0,0 +1,103 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// pngHeader builds the signature and IHDR chunk of a PNG declaring the given size, without any pixel data.
+func pngHeader(width, height uint32) []byte {
+	chunk := make([]byte, 17)
+	copy(chunk, "IHDR")
+	binary.BigEndian.PutUint32(chunk[4:8], width)
+	binary.BigEndian.PutUint32(chunk[8:12], height)
+	chunk[12], chunk[13] = 8, 2 // 8-bit RGB
+
+	buf := []byte("\x89PNG\r\n\x1a\n")
+	buf = binary.BigEndian.AppendUint32(buf, 13)
+	buf = append(buf, chunk...)
+	return binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(chunk))
+}
+
+func pngBytes(width, height int, c color.Color) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func TestDecodeImage(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		size image.Point
+		err  error
+	}{
+		{"small png", pngBytes(3, 2, color.White), image.Pt(3, 2), nil},
+		{"too many pixels", pngHeader(10000, 10000), image.Point{}, ErrImageTooLarge},
+		{"too wide", pngHeader(1<<30, 1), image.Point{}, ErrImageTooLarge},
+		{"not an image", []byte("not an image"), image.Point{}, image.ErrFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := DecodeImage(bytes.NewReader(tt.data))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("DecodeImage() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && img.Bounds().Size() != tt.size {
+				t.Errorf("DecodeImage() size = %v, want %v", img.Bounds().Size(), tt.size)
+			}
+		})
+	}
+}
+
+func TestMosaic(t *testing.T) {
+	red := image.NewUniform(color.RGBA{R: 255, A: 255})
+	blue := image.NewUniform(color.RGBA{B: 255, A: 255})
+	redImage := image.NewRGBA(image.Rect(0, 0, 30, 20))
+	blueImage := image.NewRGBA(image.Rect(0, 0, 20, 30))
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 30; x++ {
+			redImage.Set(x, y, red.C)
+			blueImage.Set(x, y, blue.C)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		images []image.Image
+		// colors of the top-left, top-right, bottom-left and bottom-right tiles
+		want [4]color.RGBA
+	}{
+		{"empty", nil, [4]color.RGBA{{A: 255}, {A: 255}, {A: 255}, {A: 255}}},
+		{"one image", []image.Image{redImage}, [4]color.RGBA{red.C.(color.RGBA), red.C.(color.RGBA), red.C.(color.RGBA), red.C.(color.RGBA)}},
+		{"two images on a diagonal", []image.Image{redImage, blueImage}, [4]color.RGBA{red.C.(color.RGBA), blue.C.(color.RGBA), blue.C.(color.RGBA), red.C.(color.RGBA)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const size = 40
+			dst := Mosaic(tt.images, size)
+			if dst.Bounds().Size() != image.Pt(size, size) {
+				t.Fatalf("Mosaic() size = %v, want %dx%d", dst.Bounds().Size(), size, size)
+			}
+			for i, at := range []image.Point{{10, 10}, {30, 10}, {10, 30}, {30, 30}} {
+				if got := dst.RGBAAt(at.X, at.Y); got != tt.want[i] {
+					t.Errorf("tile %d = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+type wavFormat struct {
+	AudioFormat   uint16
+	Channels      uint16
+	SampleRate    uint32
+	ByteRate      uint32
+	BlockAlign    uint16
+	BitsPerSample uint16
+}
+
+/*
+* readWAVHeader walks the RIFF chunks and returns the fmt chunk together with the size and offset of the data chunk
+ */
+func readWAVHeader(r io.ReadSeeker) (*wavFormat, int64, int64, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, 0, err
+	}
+
+	riff := make([]byte, 12)
+	if _, err := io.ReadFull(r, riff); err != nil {
+		return nil, 0, 0, err
+	}
+	if !bytes.Equal(riff[0:4], []byte("RIFF")) || !bytes.Equal(riff[8:12], []byte("WAVE")) {
+		return nil, 0, 0, ErrUnknownFormat
+	}
+
+	var format *wavFormat
+	offset := int64(12)
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return nil, 0, 0, err
+		}
+		offset += 8
+		chunkSize := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch string(chunk[0:4]) {
+		case "fmt ":
+			format = &wavFormat{}
+			if err := binary.Read(io.LimitReader(r, chunkSize), binary.LittleEndian, format); err != nil {
+				return nil, 0, 0, err
+			}
+		case "data":
+			if format == nil {
+				return nil, 0, 0, errors.New("media: wav data chunk before fmt chunk")
+			}
+			return format, chunkSize, offset, nil
+		}
+
+		// Chunks are padded to an even size.
+		offset += chunkSize + chunkSize%2
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+}
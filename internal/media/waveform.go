@@ -0,0 +1,235 @@
+package media
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/hajimehoshi/go-mp3"
+)
+
+// ErrUnsupportedDecode is returned for formats that cannot be decoded to PCM.
+var ErrUnsupportedDecode = errors.New("media: decoding this format is not supported")
+
+// cancelCheckFrames is how many frames are decoded between checks of the context.
+const cancelCheckFrames = 1 << 14
+
+// Peaks is a downsampled waveform: one min/max pair per bucket, scaled to int8.
+type Peaks struct {
+	Resolution int
+	Data       []int8 // min0, max0, min1, max1, ...
+}
+
+// sampleStream yields decoded frames with every channel normalised to [-1, 1].
+type sampleStream interface {
+	Frames() int64
+	Channels() int
+	Next(frame []float32) error
+}
+
+/*
+* ComputePeaks decodes the stream and builds a waveform for each requested number of buckets.
+* WAV (integer and float PCM) and MP3 are supported. Decoding stops with the context's error once it is done.
+ */
+func ComputePeaks(ctx context.Context, r io.ReadSeeker, format string, resolutions []int) ([]Peaks, error) {
+	var stream sampleStream
+	var err error
+
+	switch format {
+	case FormatWAV:
+		stream, err = newWAVStream(r)
+	case FormatMP3:
+		stream, err = newMP3Stream(r)
+	default:
+		return nil, ErrUnsupportedDecode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	total := stream.Frames()
+	if total <= 0 {
+		return nil, errNoDuration
+	}
+
+	mins := make([][]float32, len(resolutions))
+	maxs := make([][]float32, len(resolutions))
+	for i, resolution := range resolutions {
+		mins[i] = make([]float32, resolution)
+		maxs[i] = make([]float32, resolution)
+	}
+
+	frame := make([]float32, stream.Channels())
+	for index := int64(0); index < total; index++ {
+		if index%cancelCheckFrames == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if err := stream.Next(frame); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return nil, err
+		}
+
+		low, high := frame[0], frame[0]
+		for _, sample := range frame[1:] {
+			if sample < low {
+				low = sample
+			}
+			if sample > high {
+				high = sample
+			}
+		}
+
+		for i, resolution := range resolutions {
+			bucket := int(index * int64(resolution) / total)
+			if low < mins[i][bucket] {
+				mins[i][bucket] = low
+			}
+			if high > maxs[i][bucket] {
+				maxs[i][bucket] = high
+			}
+		}
+	}
+
+	peaks := make([]Peaks, len(resolutions))
+	for i, resolution := range resolutions {
+		data := make([]int8, resolution*2)
+		for bucket := 0; bucket < resolution; bucket++ {
+			data[bucket*2] = toInt8(mins[i][bucket])
+			data[bucket*2+1] = toInt8(maxs[i][bucket])
+		}
+		peaks[i] = Peaks{Resolution: resolution, Data: data}
+	}
+
+	return peaks, nil
+}
+
+func toInt8(v float32) int8 {
+	return int8(math.Round(float64(clamp(v)) * 127))
+}
+
+func clamp(v float32) float32 {
+	if v > 1 {
+		return 1
+	}
+	if v < -1 {
+		return -1
+	}
+	return v
+}
+
+type wavStream struct {
+	r        *bufio.Reader
+	format   *wavFormat
+	frames   int64
+	buf      []byte
+	isFloat  bool
+	bytesPer int
+}
+
+func newWAVStream(r io.ReadSeeker) (*wavStream, error) {
+	format, dataSize, dataOffset, err := readWAVHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if format.Channels == 0 || format.BlockAlign == 0 {
+		return nil, ErrUnsupportedDecode
+	}
+
+	// 1 is integer PCM, 3 is IEEE float and 0xFFFE is WAVE_FORMAT_EXTENSIBLE.
+	isFloat := format.AudioFormat == 3
+	if format.AudioFormat != 1 && format.AudioFormat != 3 && format.AudioFormat != 0xFFFE {
+		return nil, ErrUnsupportedDecode
+	}
+
+	bytesPer := int(format.BitsPerSample / 8)
+	if bytesPer < 1 || bytesPer > 4 || (isFloat && bytesPer != 4) {
+		return nil, ErrUnsupportedDecode
+	}
+
+	if _, err := r.Seek(dataOffset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return &wavStream{
+		r:        bufio.NewReaderSize(r, 64*1024),
+		format:   format,
+		frames:   dataSize / int64(format.BlockAlign),
+		buf:      make([]byte, format.BlockAlign),
+		isFloat:  isFloat,
+		bytesPer: bytesPer,
+	}, nil
+}
+
+func (s *wavStream) Frames() int64 { return s.frames }
+
+func (s *wavStream) Channels() int { return int(s.format.Channels) }
+
+func (s *wavStream) Next(frame []float32) error {
+	if _, err := io.ReadFull(s.r, s.buf); err != nil {
+		return err
+	}
+
+	for ch := range frame {
+		b := s.buf[ch*s.bytesPer : (ch+1)*s.bytesPer]
+		switch {
+		case s.isFloat:
+			frame[ch] = math.Float32frombits(binary.LittleEndian.Uint32(b))
+		case s.bytesPer == 1:
+			// 8-bit PCM is unsigned.
+			frame[ch] = (float32(b[0]) - 128) / 128
+		case s.bytesPer == 2:
+			frame[ch] = float32(int16(binary.LittleEndian.Uint16(b))) / 32768
+		case s.bytesPer == 3:
+			v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+			frame[ch] = float32(v) / 8388608
+		case s.bytesPer == 4:
+			frame[ch] = float32(int32(binary.LittleEndian.Uint32(b))) / 2147483648
+		}
+	}
+	return nil
+}
+
+type mp3Stream struct {
+	r      *bufio.Reader
+	frames int64
+	buf    []byte
+}
+
+func newMP3Stream(r io.ReadSeeker) (*mp3Stream, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	decoder, err := mp3.NewDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// The decoder always produces 16-bit little endian stereo.
+	return &mp3Stream{
+		r:      bufio.NewReaderSize(decoder, 64*1024),
+		frames: decoder.Length() / 4,
+		buf:    make([]byte, 4),
+	}, nil
+}
+
+func (s *mp3Stream) Frames() int64 { return s.frames }
+
+func (s *mp3Stream) Channels() int { return 2 }
+
+func (s *mp3Stream) Next(frame []float32) error {
+	if _, err := io.ReadFull(s.r, s.buf); err != nil {
+		return err
+	}
+	frame[0] = float32(int16(binary.LittleEndian.Uint16(s.buf[0:2]))) / 32768
+	frame[1] = float32(int16(binary.LittleEndian.Uint16(s.buf[2:4]))) / 32768
+	return nil
+}
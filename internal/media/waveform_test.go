@@ -0,0 +1,132 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestComputePeaks(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		format      string
+		resolutions []int
+		want        [][]int8
+		cancelled   bool
+		err         error
+	}{
+		{
+			name:        "mono buckets",
+			data:        wavBytes(1, 8000, []int16{32767, -32768, 16384, 0}),
+			format:      FormatWAV,
+			resolutions: []int{2},
+			want:        [][]int8{{-127, 127, 0, 64}},
+		},
+		{
+			name:        "several resolutions",
+			data:        wavBytes(1, 8000, []int16{-16384, 16384, 0, 32767}),
+			format:      FormatWAV,
+			resolutions: []int{1, 4},
+			want: [][]int8{
+				{-64, 127},
+				{-64, 0, 0, 64, 0, 0, 0, 127},
+			},
+		},
+		{
+			name:        "stereo takes the extremes of both channels",
+			data:        wavBytes(2, 8000, []int16{-8192, 8192, 32767, -32768}),
+			format:      FormatWAV,
+			resolutions: []int{1},
+			want:        [][]int8{{-127, 127}},
+		},
+		{
+			name:        "more buckets than frames",
+			data:        wavBytes(1, 8000, []int16{32767, -32768}),
+			format:      FormatWAV,
+			resolutions: []int{4},
+			want:        [][]int8{{0, 127, 0, 0, -127, 0, 0, 0}},
+		},
+		{
+			name:        "silence",
+			data:        wavBytes(1, 8000, make([]int16, 64)),
+			format:      FormatWAV,
+			resolutions: []int{2},
+			want:        [][]int8{{0, 0, 0, 0}},
+		},
+		{
+			name:        "no samples",
+			data:        wavBytes(1, 8000, nil),
+			format:      FormatWAV,
+			resolutions: []int{2},
+			err:         errNoDuration,
+		},
+		{
+			name:        "unsupported format",
+			data:        flacBytes(44100, 44100),
+			format:      FormatFLAC,
+			resolutions: []int{2},
+			err:         ErrUnsupportedDecode,
+		},
+		{
+			name:        "cancelled context",
+			data:        wavBytes(1, 8000, make([]int16, 64)),
+			format:      FormatWAV,
+			resolutions: []int{2},
+			cancelled:   true,
+			err:         context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelled {
+				cancel()
+			}
+
+			peaks, err := ComputePeaks(ctx, bytes.NewReader(tt.data), tt.format, tt.resolutions)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ComputePeaks() error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+
+			if len(peaks) != len(tt.want) {
+				t.Fatalf("ComputePeaks() returned %d resolutions, want %d", len(peaks), len(tt.want))
+			}
+			for i, p := range peaks {
+				if p.Resolution != tt.resolutions[i] {
+					t.Errorf("peaks[%d].Resolution = %d, want %d", i, p.Resolution, tt.resolutions[i])
+				}
+				if !reflect.DeepEqual(p.Data, tt.want[i]) {
+					t.Errorf("peaks[%d].Data = %v, want %v", i, p.Data, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestToInt8(t *testing.T) {
+	tests := []struct {
+		in   float32
+		want int8
+	}{
+		{0, 0},
+		{1, 127},
+		{-1, -127},
+		{0.5, 64},
+		{2, 127},
+		{-3, -127},
+	}
+
+	for _, tt := range tests {
+		if got := toInt8(tt.in); got != tt.want {
+			t.Errorf("toInt8(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
@@ -19,18 +20,37 @@ type CustomClaims struct {
 	jwt.StandardClaims
 }
 
-func FindUserByIdAndToken(userID uint, tokenString string) (*models.User, error) {
+func FindUserByIdAndToken(userID uint, tokenString string) (*models.User, *models.Token, error) {
 	var token models.Token
-	if err := initializers.DB.Where("user_id = ? AND token = ?", userID, tokenString).First(&token).Error; err != nil {
-		return nil, err
+	if err := initializers.DB.Where("user_id = ? AND token = ? AND type IN ?", userID, tokenString, []string{models.TokenTypeAccess, "auth"}).
+		First(&token).Error; err != nil {
+		return nil, nil, err
 	}
 
 	var user models.User
-	if err := initializers.DB.Preload("Tokens").First(&user, userID).Error; err != nil {
-		return nil, err
+	if err := initializers.DB.First(&user, userID).Error; err != nil {
+		return nil, nil, err
 	}
 
-	return &user, nil
+	return &user, &token, nil
+}
+
+// lastSeenInterval is how stale the last seen time of a session may get before a request refreshes it.
+const lastSeenInterval = time.Minute
+
+/*
+* touchSession records that a session was used, at most once per lastSeenInterval to spare writes
+ */
+func touchSession(token *models.Token) {
+	now := time.Now()
+	if token.Family == "" || now.Sub(token.LastSeenAt) < lastSeenInterval {
+		return
+	}
+
+	initializers.DB.Model(&models.Token{}).
+		Where("family = ? AND last_seen_at < ?", token.Family, now.Add(-lastSeenInterval)).
+		UpdateColumn("last_seen_at", now)
+	token.LastSeenAt = now
 }
 
 /*
@@ -56,14 +76,16 @@ func IsAuthenticated(c *gin.Context) {
 	}
 
 	if claims, ok := token.Claims.(*CustomClaims); ok && token.Valid {
-		user, err := FindUserByIdAndToken(claims.UserID, tokenString)
+		user, session, err := FindUserByIdAndToken(claims.UserID, tokenString)
 		if err != nil || user == nil {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized access here"})
 			c.Abort()
 			return
 		}
 
+		touchSession(session)
 		c.Set("user", user)
+		c.Set("token", session)
 	} else {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid token"})
 		c.Abort()
@@ -73,6 +95,34 @@ func IsAuthenticated(c *gin.Context) {
 	c.Next()
 }
 
+/*
+* OptionalAuthentication identifies the caller when a valid token is sent, and lets anonymous requests through
+ */
+func OptionalAuthentication(c *gin.Context) {
+	tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if tokenString == "" {
+		c.Next()
+		return
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+
+	if err == nil && token.Valid {
+		if claims, ok := token.Claims.(*CustomClaims); ok {
+			user, session, err := FindUserByIdAndToken(claims.UserID, tokenString)
+			if err == nil {
+				touchSession(session)
+				c.Set("user", user)
+				c.Set("token", session)
+			}
+		}
+	}
+
+	c.Next()
+}
+
 func IsAdmin(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
@@ -47,6 +47,10 @@ func processError(c *gin.Context, err error) {
 	case *validator.InvalidValidationError:
 		code = http.StatusBadRequest
 		message = "Invalid data structure"
+	case *response.HTTPError:
+		code = e.Code
+		message = e.Message
+		data = e.Data
 	default:
 		code = http.StatusInternalServerError
 		message = "Internal Server Error"
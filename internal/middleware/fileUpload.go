@@ -1,12 +1,47 @@
 package middleware
 
 import (
+	"backend/internal/initializers"
+	response "backend/pkg"
+	"errors"
+	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 
+	"github.com/gabriel-vasile/mimetype"
 	"github.com/gin-gonic/gin"
 )
 
+// Megabyte is the unit of the configured upload and download size limits.
+const Megabyte = 1 << 20
+
+// FileRule restricts what may be uploaded in a multipart file field.
+type FileRule struct {
+	Allowed []string
+	MaxSize int64
+}
+
+var (
+	AudioMIMETypes = []string{"audio/mpeg", "audio/wav", "audio/flac", "audio/ogg", "audio/mp4", "audio/x-m4a", "audio/aac", "audio/webm"}
+	ImageMIMETypes = []string{"image/jpeg", "image/png", "image/webp", "image/gif"}
+)
+
+/*
+* UploadRules returns the allowlist and size limit of every accepted file field.
+* Limits are read from UPLOAD_MAX_AUDIO_MB and UPLOAD_MAX_IMAGE_MB.
+ */
+func UploadRules() map[string]FileRule {
+	audioMax := int64(initializers.GetEnvInt("UPLOAD_MAX_AUDIO_MB", 32)) * Megabyte
+	imageMax := int64(initializers.GetEnvInt("UPLOAD_MAX_IMAGE_MB", 5)) * Megabyte
+
+	return map[string]FileRule{
+		"audioFile": {Allowed: AudioMIMETypes, MaxSize: audioMax},
+		"coverFile": {Allowed: ImageMIMETypes, MaxSize: imageMax},
+		"picFile":   {Allowed: ImageMIMETypes, MaxSize: imageMax},
+	}
+}
+
 /*
 * This method extracts and validates a file upload from an incoming request
  */
@@ -18,7 +53,21 @@ func FileParserMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if err := c.Request.ParseMultipartForm(32 << 20); err != nil { // 32 MB size
+		rules := UploadRules()
+
+		var maxRequestSize int64 = Megabyte // room for the text fields
+		for _, rule := range rules {
+			maxRequestSize += rule.MaxSize
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRequestSize)
+
+		if err := c.Request.ParseMultipartForm(32 << 20); err != nil { // 32 MB kept in memory, the rest spills to disk
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.Error(response.NewHTTPError(http.StatusRequestEntityTooLarge, "Request body too large", gin.H{"limit": maxRequestSize}))
+				c.Abort()
+				return
+			}
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Bad request, error parsing form data"})
 			c.Abort()
 			return
@@ -35,9 +84,24 @@ func FileParserMiddleware() gin.HandlerFunc {
 
 		files := make(map[string]*multipart.FileHeader)
 		for key, fileHeaders := range c.Request.MultipartForm.File {
-			if len(fileHeaders) > 0 {
-				files[key] = fileHeaders[0]
+			if len(fileHeaders) == 0 {
+				continue
+			}
+
+			rule, ok := rules[key]
+			if !ok {
+				c.Error(response.NewHTTPError(http.StatusBadRequest, "Unexpected file field", gin.H{"field": key}))
+				c.Abort()
+				return
 			}
+
+			if uploadErr := CheckUploadedFile(key, fileHeaders[0], rule); uploadErr != nil {
+				c.Error(uploadErr)
+				c.Abort()
+				return
+			}
+
+			files[key] = fileHeaders[0]
 		}
 
 		c.Set("files", files)
@@ -46,6 +110,61 @@ func FileParserMiddleware() gin.HandlerFunc {
 	}
 }
 
+/*
+* CheckUploadedFile sniffs the content of an uploaded file and checks it against the rule of its field
+ */
+func CheckUploadedFile(field string, fileHeader *multipart.FileHeader, rule FileRule) *response.HTTPError {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return response.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s could not be read", field), gin.H{"field": field})
+	}
+	defer file.Close()
+
+	return CheckUploadedContent(field, file, fileHeader.Size, rule)
+}
+
+/*
+* CheckUploadedContent checks the size and sniffed content type of a file against a rule
+ */
+func CheckUploadedContent(field string, r io.Reader, size int64, rule FileRule) *response.HTTPError {
+	if size > rule.MaxSize {
+		return response.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("%s is too large", field), gin.H{
+			"field":    field,
+			"size":     size,
+			"max_size": rule.MaxSize,
+		})
+	}
+
+	detected, err := mimetype.DetectReader(r)
+	if err != nil {
+		return response.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s could not be read", field), gin.H{"field": field})
+	}
+
+	if !IsAllowedType(detected, rule.Allowed) {
+		return response.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("%s has an unsupported file type", field), gin.H{
+			"field":    field,
+			"detected": detected.String(),
+			"allowed":  rule.Allowed,
+		})
+	}
+
+	return nil
+}
+
+/*
+* IsAllowedType reports whether the detected type, or one of its parents, is in the allowlist
+ */
+func IsAllowedType(detected *mimetype.MIME, allowed []string) bool {
+	for mtype := detected; mtype != nil; mtype = mtype.Parent() {
+		for _, candidate := range allowed {
+			if mtype.Is(candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func startsWith(s, prefix string) bool {
 	if len(s) < len(prefix) {
 		return false
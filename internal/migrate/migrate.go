@@ -16,7 +16,18 @@ func main() {
 	initializers.DB.AutoMigrate(&models.UserPasswordReset{})
 	initializers.DB.AutoMigrate(&models.Audio{})
 	initializers.DB.AutoMigrate(&models.Playlist{})
+	initializers.DB.AutoMigrate(&models.PlaylistAudio{})
+	initializers.DB.AutoMigrate(&models.PlaylistCollaborator{})
+	initializers.DB.AutoMigrate(&models.PlaylistActivity{})
 	initializers.DB.AutoMigrate(&models.Token{})
+	models.MigrateFavorites(initializers.DB)
 	initializers.DB.AutoMigrate(&models.Favorite{})
 	initializers.DB.AutoMigrate(&models.User_Relations{})
+	initializers.DB.AutoMigrate(&models.History{})
+	initializers.DB.AutoMigrate(&models.UploadSession{})
+	initializers.DB.AutoMigrate(&models.Waveform{})
+	initializers.DB.AutoMigrate(&models.ShareLink{})
+	models.MigrateShareLinks(initializers.DB)
+	initializers.DB.AutoMigrate(&models.Activity{})
+	models.CreateSearchIndexes(initializers.DB)
 }
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// Kinds of activities shown in the following feed.
+const (
+	ActivityUpload          = "upload"
+	ActivityPlaylistCreated = "playlist_created"
+	ActivityTracksAdded     = "tracks_added"
+)
+
+// Activity is something a user did that their followers see in their feed.
+// Feeds are assembled on read from the activities of followed users, so rows
+// are written once per action regardless of the follower count.
+type Activity struct {
+	ID         uint             `gorm:"primaryKey;index:idx_activities_actor,priority:3"`
+	ActorID    uint             `gorm:"column:actor_id;not null;index:idx_activities_actor,priority:1"`
+	Actor      User             `gorm:"foreignKey:ActorID"`
+	Action     string           `gorm:"column:action;not null"`
+	AudioID    *uint            `gorm:"column:audio_id;index"`
+	PlaylistID *uint            `gorm:"column:playlist_id;index"`
+	AudioIDs   JSONIntegerArray `gorm:"column:audio_ids;type:jsonb"`
+	CreatedAt  time.Time        `gorm:"column:created_at;index:idx_activities_actor,priority:2"`
+}
@@ -1,19 +1,38 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+const (
+	AudioPublic  = "public"
+	AudioPrivate = "private" // unreleased: readable by the owner and holders of a share link
+)
+
+var AudioVisibilities = []string{AudioPublic, AudioPrivate}
+
 type Audio struct {
 	gorm.Model
-	Title         string     `gorm:"column:name" validate:"required,min=10,max=200"`
-	About         string     `gorm:"column:about" validate:"required max=1000"`
-	Owner         uint       `gorm:"foreignKey:UserID"`
-	AudioURL      string     `gorm:"column:audio_url" validate:"omitempty,url"`
-	AudioPublicID string     `gorm:"column:audio_public_id" validate:"omitempty,alphanum"`
-	CoverURL      string     `gorm:"column:cover_url" validate:"omitempty,url"`
-	CoverPublicID string     `gorm:"column:cover_public_id" validate:"omitempty,alphanum"`
-	Duration      uint       `gorm:"column:duration"`
-	Category      string     `gorm:"column:category" validate:"required"`
-	Playlists     []Playlist `gorm:"many2many:playlist_audios;"`
+	Title          string `gorm:"column:name" validate:"required,min=10,max=200"`
+	About          string `gorm:"column:about" validate:"required max=1000"`
+	OwnerID        uint   `gorm:"column:owner;index"`
+	Owner          *User  `gorm:"foreignKey:OwnerID;-:migration" json:",omitempty"` // no FK constraint: legacy rows may reference removed users
+	AudioURL       string `gorm:"column:audio_url" validate:"omitempty,url"`
+	AudioPublicID  string `gorm:"column:audio_public_id" validate:"omitempty,alphanum"`
+	CoverURL       string `gorm:"column:cover_url" validate:"omitempty,url"`
+	CoverPublicID  string `gorm:"column:cover_public_id" validate:"omitempty,alphanum"`
+	Duration       uint   `gorm:"column:duration"`
+	Artist         string `gorm:"column:artist"`
+	Album          string `gorm:"column:album"`
+	TrackNumber    int    `gorm:"column:track_number"`
+	Year           int    `gorm:"column:year"`
+	Genre          string `gorm:"column:genre"`
+	WaveformStatus string `gorm:"column:waveform_status"`
+	// WaveformQueuedAt is when generation was last started, so pending jobs lost to a restart can be retried.
+	WaveformQueuedAt *time.Time `gorm:"column:waveform_queued_at"`
+	Category         string     `gorm:"column:category" validate:"required"`
+	Visibility       string     `gorm:"column:visibility;not null;default:public;index"`
+	Playlists        []Playlist `gorm:"many2many:playlist_audios;"`
 }
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Roles a user can have on a playlist. Owners are not stored as collaborators.
+const (
+	PlaylistRoleOwner  = "owner"
+	PlaylistRoleEditor = "editor"
+	PlaylistRoleViewer = "viewer"
+)
+
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+)
+
+// PlaylistCollaborator is a user invited to a playlist. The role only takes
+// effect once the invitation has been accepted.
+type PlaylistCollaborator struct {
+	gorm.Model
+	PlaylistID uint       `gorm:"column:playlist_id;not null;uniqueIndex:idx_playlist_collaborator"`
+	UserID     uint       `gorm:"column:user_id;not null;uniqueIndex:idx_playlist_collaborator;index"`
+	User       User       `gorm:"foreignKey:UserID"`
+	Role       string     `gorm:"column:role;not null" validate:"oneof=viewer editor"`
+	Status     string     `gorm:"column:status;not null;default:pending"`
+	InvitedBy  uint       `gorm:"column:invited_by"`
+	AcceptedAt *time.Time `gorm:"column:accepted_at"`
+}
+
+// PlaylistActivity is an entry of the edit log of a playlist.
+type PlaylistActivity struct {
+	ID         uint      `gorm:"primaryKey"`
+	PlaylistID uint      `gorm:"column:playlist_id;not null;index:idx_playlist_activity,priority:1"`
+	UserID     uint      `gorm:"column:user_id;not null"`
+	User       User      `gorm:"foreignKey:UserID"`
+	Action     string    `gorm:"column:action;not null"`
+	Details    string    `gorm:"column:details;type:jsonb"`
+	CreatedAt  time.Time `gorm:"column:created_at;index:idx_playlist_activity,priority:2"`
+}
+
+type InviteCollaborator struct {
+	UserID uint   `json:"userId" validate:"required"`
+	Role   string `json:"role" validate:"required,oneof=viewer editor"`
+}
@@ -11,7 +11,9 @@ type UserEmailVerification struct {
 	VerificationID uint      `gorm:"primaryKey"`
 	Token          string    `gorm:"column:token;not null"`
 	CreatedAt      time.Time `gorm:"default:current_timestamp"`
-	UserID         uint      `gorm:"foreignKey:UserID"`
+	ExpiresAt      time.Time `gorm:"column:expires_at"`
+	Attempts       int       `gorm:"column:attempts;not null;default:0"`
+	UserID         uint      `gorm:"index;foreignKey:UserID"`
 }
 
 func (uev *UserEmailVerification) BeforeSave(*gorm.DB) error {
@@ -23,6 +25,11 @@ func (uev *UserEmailVerification) BeforeSave(*gorm.DB) error {
 	return nil
 }
 
+// IsExpired reports whether the OTP has outlived its TTL.
+func (uev *UserEmailVerification) IsExpired() bool {
+	return time.Now().After(uev.ExpiresAt)
+}
+
 type VerifyEmail struct {
 	Token  string `gorm:"column:token_hash;not null"`
 	UserID uint   `gorm:"foreignKey:UserID"`
@@ -1,8 +1,70 @@
 package models
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of items a user can favorite.
+const (
+	FavoriteAudio    = "audio"
+	FavoritePlaylist = "playlist"
+	FavoriteUser     = "user"
+)
+
+var FavoriteTargets = []string{FavoriteAudio, FavoritePlaylist, FavoriteUser}
+
+// Favorite is a like of an audio, playlist or user. TargetID refers to the
+// table named by TargetType.
 type Favorite struct {
-	UserID  uint  `gorm:"primaryKey"`
-	AudioID uint  `gorm:"primaryKey"`
-	User    User  `gorm:"foreignKey:UserID"`
-	Audio   Audio `gorm:"foreignKey:AudioID"`
+	UserID     uint      `gorm:"primaryKey;index:idx_favorites_recent,priority:1"`
+	TargetType string    `gorm:"column:target_type;primaryKey;default:audio;index:idx_favorites_recent,priority:2;index:idx_favorites_target,priority:1"`
+	TargetID   uint      `gorm:"column:target_id;primaryKey;index:idx_favorites_target,priority:2"`
+	CreatedAt  time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP;index:idx_favorites_recent,priority:3"`
+}
+
+// favoriteMigrations move the audio-only favorites table to the polymorphic
+// layout and fold in the unused user_favorites join table. Every statement is
+// idempotent.
+var favoriteMigrations = []string{
+	`DO $$ BEGIN
+		IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'favorites' AND column_name = 'audio_id') THEN
+			ALTER TABLE favorites DROP CONSTRAINT IF EXISTS fk_favorites_audio;
+			ALTER TABLE favorites RENAME COLUMN audio_id TO target_id;
+			ALTER TABLE favorites ADD COLUMN target_type text NOT NULL DEFAULT 'audio';
+			ALTER TABLE favorites DROP CONSTRAINT IF EXISTS favorites_pkey;
+			ALTER TABLE favorites ADD PRIMARY KEY (user_id, target_type, target_id);
+		END IF;
+	END $$`,
+	`DO $$ BEGIN
+		IF to_regclass('user_favorites') IS NOT NULL AND to_regclass('favorites') IS NOT NULL THEN
+			INSERT INTO favorites (user_id, target_type, target_id, created_at)
+			SELECT user_id, 'audio', audio_id, CURRENT_TIMESTAMP FROM user_favorites
+			ON CONFLICT DO NOTHING;
+			DROP TABLE user_favorites;
+		END IF;
+	END $$`,
+}
+
+/*
+* MigrateFavorites converts favorites created before playlists and users could be liked.
+* It must run before AutoMigrate of Favorite.
+ */
+func MigrateFavorites(db *gorm.DB) error {
+	for _, statement := range favoriteMigrations {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type FavoriteRequest struct {
+	Type     string `json:"type" form:"type" validate:"omitempty,oneof=audio playlist user"`
+	TargetID uint   `json:"targetId" form:"targetId" validate:"required"`
+}
+
+type FavoriteStatusRequest struct {
+	AudioIDs []uint `json:"audioIds" validate:"required,min=1,max=100"`
 }
@@ -1,9 +1,27 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
 
+	"gorm.io/gorm"
+)
+
+// History is a single play event. Rows are written per play so they can be
+// paginated and deleted individually.
 type History struct {
 	gorm.Model
-	Owner uint             `gorm:"column:owner_id;foreignKey:UserID"`
-	Songs JSONIntegerArray `gorm:"column:songs;type:json;default:[]"`
+	Owner            uint      `gorm:"column:owner_id;not null;index:idx_history_owner_played,priority:1"`
+	AudioID          uint      `gorm:"column:audio_id;not null;index"`
+	Audio            Audio     `gorm:"foreignKey:AudioID"`
+	Position         uint      `gorm:"column:position"`
+	DurationListened uint      `gorm:"column:duration_listened"`
+	Client           string    `gorm:"column:client"`
+	PlayedAt         time.Time `gorm:"column:played_at;not null;index:idx_history_owner_played,priority:2"`
+}
+
+type RecordPlay struct {
+	AudioID          uint   `json:"audioId" validate:"required"`
+	Position         uint   `json:"position"`
+	DurationListened uint   `json:"durationListened"`
+	Client           string `json:"client" validate:"max=100"`
 }
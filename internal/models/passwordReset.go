@@ -2,11 +2,39 @@ package models
 
 import (
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type UserPasswordReset struct {
 	ResetID   uint      `gorm:"primaryKey"`
 	Token     string    `gorm:"column:token;not null"`
 	CreatedAt time.Time `gorm:"default:current_timestamp"`
-	UserID    uint      `gorm:"foreignKey:UserID"`
+	ExpiresAt time.Time `gorm:"column:expires_at;not null"`
+	UserID    uint      `gorm:"index;foreignKey:UserID"`
+}
+
+func (upr *UserPasswordReset) BeforeSave(*gorm.DB) error {
+	hashedToken, err := bcrypt.GenerateFromPassword([]byte(upr.Token), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	upr.Token = string(hashedToken)
+	return nil
+}
+
+// IsExpired reports whether the reset token can no longer be used.
+func (upr *UserPasswordReset) IsExpired() bool {
+	return time.Now().After(upr.ExpiresAt)
+}
+
+type ForgotPassword struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPassword struct {
+	UserID   uint   `json:"userId" validate:"required"`
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
 }
@@ -4,7 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
-	"math/rand"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -30,6 +30,15 @@ func (j JSONIntegerArray) Value() (driver.Value, error) {
 	return json.Marshal(j)
 }
 
+const (
+	PlaylistPublic   = "public"
+	PlaylistPrivate  = "private"
+	PlaylistUnlisted = "unlisted" // readable by anyone holding the share token
+	PlaylistAuto     = "auto"     // smart playlist, readable like a private one
+)
+
+var PlaylistVisibilities = []string{PlaylistPublic, PlaylistPrivate, PlaylistUnlisted, PlaylistAuto}
+
 type Playlist struct {
 	gorm.Model
 	Title         string  `gorm:"column:title;validate:min=10,max=200"`
@@ -37,15 +46,57 @@ type Playlist struct {
 	Audios        []Audio `gorm:"many2many:playlist_audios;"`
 	CoverURL      string  `gorm:"column:cover_url" validate:"omitempty,url"`
 	CoverPublicID string  `gorm:"column:cover_public_id" validate:"omitempty,alphanum"`
-	Visibility    string  `gorm:"column:visibility;default:public;validate:oneof=public private auto"`
+	Visibility    string  `gorm:"column:visibility;default:public;validate:oneof=public private unlisted auto"`
+	Version       uint    `gorm:"column:version;not null;default:1"`
+	// CustomCover is set when the owner uploaded the cover. Otherwise the cover is
+	// a mosaic of the first track covers, identified by CoverSignature.
+	CustomCover    bool   `gorm:"column:custom_cover;not null;default:false"`
+	CoverSignature string `gorm:"column:cover_signature"`
+	// ForkedFromID is the playlist this one was copied from.
+	ForkedFromID *uint `gorm:"column:forked_from_id;index"`
+	// Rules define the tracks of playlists with "auto" visibility.
+	Rules *SmartPlaylistRules `gorm:"column:rules;type:jsonb"`
+}
+
+// PlaylistAudio is a row of the playlist_audios join table. Position orders
+// the tracks of a playlist starting at 0.
+type PlaylistAudio struct {
+	PlaylistID uint      `gorm:"column:playlist_id;primaryKey"`
+	AudioID    uint      `gorm:"column:audio_id;primaryKey"`
+	Position   int       `gorm:"column:position;not null;default:0;index:idx_playlist_audios_position"`
+	AddedBy    uint      `gorm:"column:added_by"`
+	CreatedAt  time.Time `gorm:"column:created_at"`
+}
+
+func (PlaylistAudio) TableName() string {
+	return "playlist_audios"
 }
 
-func (p *Playlist) SetRandomCoverURL(db *gorm.DB) error {
-	if len(p.Audios) > 0 {
-		randomIndex := rand.Intn(len(p.Audios))
-		p.CoverURL = p.Audios[randomIndex].CoverURL
-	} else {
-		p.CoverURL = "https://www.gstatic.com/youtube/media/ytm/images/pbg/playlist-empty-state-@576.png"
+// EmptyPlaylistCoverURL is shown for playlists without any track cover.
+const EmptyPlaylistCoverURL = "https://www.gstatic.com/youtube/media/ytm/images/pbg/playlist-empty-state-@576.png"
+
+/*
+* DisplayCoverURL returns the cover of the playlist, falling back to the empty state image
+ */
+func (p *Playlist) DisplayCoverURL() string {
+	if p.CoverURL == "" {
+		return EmptyPlaylistCoverURL
 	}
-	return nil
+	return p.CoverURL
+}
+
+type AddPlaylistTracks struct {
+	AudioIDs []uint `json:"audioIds" validate:"required,min=1,max=100"`
+	Position *int   `json:"position" validate:"omitempty,min=0"`
+	Version  *uint  `json:"version"`
+}
+
+type RemovePlaylistTracks struct {
+	AudioIDs []uint `json:"audioIds" validate:"required,min=1,max=100"`
+	Version  *uint  `json:"version"`
+}
+
+type MovePlaylistTrack struct {
+	Position *int  `json:"position" validate:"required,min=0"`
+	Version  *uint `json:"version" validate:"required"`
 }
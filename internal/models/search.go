@@ -0,0 +1,48 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// searchIndexes adds weighted full-text search vectors to the searchable
+// tables and trigram indexes for suggestions. AutoMigrate cannot express
+// these, so they are created here; every statement is idempotent.
+var searchIndexes = []string{
+	`ALTER TABLE audios ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce(artist, '')), 'B') ||
+		setweight(to_tsvector('english', coalesce(category, '')), 'B') ||
+		setweight(to_tsvector('english', coalesce(about, '')), 'C')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_audios_search ON audios USING GIN (search_vector)`,
+
+	`ALTER TABLE playlists ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', coalesce(title, '')), 'A')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_playlists_search ON playlists USING GIN (search_vector)`,
+
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce(bio, '')), 'C')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_users_search ON users USING GIN (search_vector)`,
+
+	// Trigram indexes back the typo-tolerant suggestions.
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`CREATE INDEX IF NOT EXISTS idx_audios_name_trgm ON audios USING GIN (name gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_audios_artist_trgm ON audios USING GIN (artist gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_audios_category_trgm ON audios USING GIN (category gin_trgm_ops)`,
+}
+
+/*
+* CreateSearchIndexes creates the full-text search columns and indexes.
+* It must run after the tables have been migrated.
+ */
+func CreateSearchIndexes(db *gorm.DB) error {
+	for _, statement := range searchIndexes {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
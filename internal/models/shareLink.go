@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Resources a share link can point to.
+const (
+	ShareResourcePlaylist = "playlist"
+	ShareResourceAudio    = "audio"
+)
+
+// ShareLink grants read access to a playlist or an audio to anyone holding its
+// token, until it expires or is revoked. Opening the link counts a use, and it
+// cannot be opened again once it has been used MaxUses times.
+type ShareLink struct {
+	gorm.Model
+	Token        string     `gorm:"column:token;not null;uniqueIndex"`
+	ResourceType string     `gorm:"column:resource_type;not null;index:idx_share_link_resource,priority:1"`
+	ResourceID   uint       `gorm:"column:resource_id;not null;index:idx_share_link_resource,priority:2"`
+	OwnerID      uint       `gorm:"column:owner_id;not null;index"`
+	ExpiresAt    *time.Time `gorm:"column:expires_at"`
+	MaxUses      *int       `gorm:"column:max_uses"`
+	Uses         int        `gorm:"column:uses;not null;default:0"`
+	PasswordHash string     `gorm:"column:password_hash" json:"-"`
+	RevokedAt    *time.Time `gorm:"column:revoked_at"`
+	// Unlisted marks the standing link of an unlisted playlist. It only grants
+	// access while the playlist is unlisted.
+	Unlisted bool `gorm:"column:unlisted;not null;default:false"`
+}
+
+// shareLinkMigrations move the share tokens of unlisted playlists into
+// share_links. Every statement is idempotent.
+var shareLinkMigrations = []string{
+	`DO $$ BEGIN
+		IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'playlists' AND column_name = 'share_token') THEN
+			INSERT INTO share_links (created_at, updated_at, token, resource_type, resource_id, owner_id, uses, unlisted)
+			SELECT CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, share_token, 'playlist', id, owner_id, 0, true
+			FROM playlists WHERE share_token IS NOT NULL AND deleted_at IS NULL
+			ON CONFLICT (token) DO NOTHING;
+			ALTER TABLE playlists DROP COLUMN share_token;
+		END IF;
+	END $$`,
+}
+
+/*
+* MigrateShareLinks converts share tokens stored on playlists into unlisted share links.
+* It must run after AutoMigrate of ShareLink.
+ */
+func MigrateShareLinks(db *gorm.DB) error {
+	for _, statement := range shareLinkMigrations {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+* Active reports whether the link still grants access
+ */
+func (l *ShareLink) Active(now time.Time) bool {
+	if l.RevokedAt != nil {
+		return false
+	}
+	return l.ExpiresAt == nil || now.Before(*l.ExpiresAt)
+}
+
+/*
+* Exhausted reports whether the link has been used as often as allowed
+ */
+func (l *ShareLink) Exhausted() bool {
+	return l.MaxUses != nil && l.Uses >= *l.MaxUses
+}
+
+type CreateShareLink struct {
+	ResourceType string `json:"resourceType" validate:"required,oneof=playlist audio"`
+	ResourceID   uint   `json:"resourceId" validate:"required"`
+	ExpiresIn    *int   `json:"expiresIn" validate:"omitempty,min=60"` // seconds
+	MaxUses      *int   `json:"maxUses" validate:"omitempty,min=1"`
+	Password     string `json:"password" validate:"omitempty,min=4,max=72"`
+}
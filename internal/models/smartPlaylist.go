@@ -0,0 +1,199 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	maxSmartRules  = 20
+	maxSmartGroups = 5
+	maxSmartLimit  = 200
+)
+
+// SmartRule is a single condition of a smart playlist.
+//
+//	category, genre, artist: op in | not_in, value is a list of strings
+//	artist, title:           op contains, value is a string
+//	owner:                   op in | not_in, value is a list of user IDs
+//	followed:                op is, value true for audios by people the owner follows
+//	favorited_within:        op lt, value is a number of days
+//	uploaded_within:         op lt, value is a number of days
+//	duration:                op lt | gt, value is seconds; op between, value is [min, max]
+type SmartRule struct {
+	Field string          `json:"field"`
+	Op    string          `json:"op"`
+	Value json.RawMessage `json:"value"`
+}
+
+// SmartRuleGroup combines rules with AND (match "all") or OR (match "any").
+type SmartRuleGroup struct {
+	Match string      `json:"match"`
+	Rules []SmartRule `json:"rules"`
+}
+
+// SmartPlaylistRules defines the tracks of an "auto" playlist. Top-level
+// rules and groups are combined according to Match.
+type SmartPlaylistRules struct {
+	Match  string           `json:"match"`
+	Rules  []SmartRule      `json:"rules"`
+	Groups []SmartRuleGroup `json:"groups,omitempty"`
+	Sort   string           `json:"sort"`
+	Limit  int              `json:"limit"`
+}
+
+var smartRuleOps = map[string][]string{
+	"category":         {"in", "not_in"},
+	"genre":            {"in", "not_in"},
+	"artist":           {"in", "not_in", "contains"},
+	"title":            {"contains"},
+	"owner":            {"in", "not_in"},
+	"followed":         {"is"},
+	"favorited_within": {"lt"},
+	"uploaded_within":  {"lt"},
+	"duration":         {"lt", "gt", "between"},
+}
+
+var SmartPlaylistSorts = []string{"newest", "oldest", "title", "duration", "most_played", "random"}
+
+func (r *SmartPlaylistRules) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		s, isString := value.(string)
+		if !isString {
+			return errors.New("type assertion to []byte failed")
+		}
+		b = []byte(s)
+	}
+
+	return json.Unmarshal(b, r)
+}
+
+func (r *SmartPlaylistRules) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	return json.Marshal(r)
+}
+
+/*
+* Normalize fills in defaults and checks that every rule is well formed
+ */
+func (r *SmartPlaylistRules) Normalize() error {
+	if r.Match == "" {
+		r.Match = "all"
+	}
+	if r.Sort == "" {
+		r.Sort = "newest"
+	}
+	if r.Limit == 0 {
+		r.Limit = 50
+	}
+
+	if r.Match != "all" && r.Match != "any" {
+		return errors.New("match must be 'all' or 'any'")
+	}
+	if !contains(SmartPlaylistSorts, r.Sort) {
+		return fmt.Errorf("sort must be one of %v", SmartPlaylistSorts)
+	}
+	if r.Limit < 1 || r.Limit > maxSmartLimit {
+		return fmt.Errorf("limit must be between 1 and %d", maxSmartLimit)
+	}
+	if len(r.Rules) == 0 && len(r.Groups) == 0 {
+		return errors.New("at least one rule is required")
+	}
+	if len(r.Groups) > maxSmartGroups {
+		return fmt.Errorf("at most %d groups are allowed", maxSmartGroups)
+	}
+
+	count := len(r.Rules)
+	for i := range r.Groups {
+		group := &r.Groups[i]
+		if group.Match == "" {
+			group.Match = "all"
+		}
+		if group.Match != "all" && group.Match != "any" {
+			return errors.New("group match must be 'all' or 'any'")
+		}
+		if len(group.Rules) == 0 {
+			return errors.New("groups must contain at least one rule")
+		}
+		count += len(group.Rules)
+		for _, rule := range group.Rules {
+			if err := rule.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	if count > maxSmartRules {
+		return fmt.Errorf("at most %d rules are allowed", maxSmartRules)
+	}
+
+	for _, rule := range r.Rules {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rule SmartRule) validate() error {
+	ops, ok := smartRuleOps[rule.Field]
+	if !ok {
+		return fmt.Errorf("unknown rule field '%s'", rule.Field)
+	}
+	if !contains(ops, rule.Op) {
+		return fmt.Errorf("operator '%s' is not supported for '%s'", rule.Op, rule.Field)
+	}
+
+	var err error
+	switch {
+	case rule.Op == "contains":
+		var v string
+		if err = json.Unmarshal(rule.Value, &v); err == nil && v == "" {
+			err = errors.New("empty")
+		}
+	case rule.Field == "owner":
+		var v []uint
+		if err = json.Unmarshal(rule.Value, &v); err == nil && len(v) == 0 {
+			err = errors.New("empty")
+		}
+	case rule.Op == "in" || rule.Op == "not_in":
+		var v []string
+		if err = json.Unmarshal(rule.Value, &v); err == nil && len(v) == 0 {
+			err = errors.New("empty")
+		}
+	case rule.Op == "is":
+		var v bool
+		err = json.Unmarshal(rule.Value, &v)
+	case rule.Op == "between":
+		var v []float64
+		if err = json.Unmarshal(rule.Value, &v); err == nil && (len(v) != 2 || v[0] > v[1]) {
+			err = errors.New("range")
+		}
+	default:
+		var v float64
+		if err = json.Unmarshal(rule.Value, &v); err == nil && v < 0 {
+			err = errors.New("negative")
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid value for '%s' rule", rule.Field)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func rule(field, op, value string) SmartRule {
+	return SmartRule{Field: field, Op: op, Value: json.RawMessage(value)}
+}
+
+func TestSmartPlaylistRulesNormalize(t *testing.T) {
+	tooMany := make([]SmartRule, maxSmartRules+1)
+	for i := range tooMany {
+		tooMany[i] = rule("title", "contains", `"a"`)
+	}
+
+	tests := []struct {
+		name  string
+		rules SmartPlaylistRules
+		want  *SmartPlaylistRules
+		err   bool
+	}{
+		{
+			name:  "defaults",
+			rules: SmartPlaylistRules{Rules: []SmartRule{rule("genre", "in", `["jazz"]`)}},
+			want:  &SmartPlaylistRules{Match: "all", Sort: "newest", Limit: 50},
+		},
+		{
+			name: "group defaults",
+			rules: SmartPlaylistRules{Match: "any", Sort: "random", Limit: 10, Groups: []SmartRuleGroup{
+				{Rules: []SmartRule{rule("followed", "is", `true`)}},
+			}},
+			want: &SmartPlaylistRules{Match: "any", Sort: "random", Limit: 10},
+		},
+		{name: "every field", rules: SmartPlaylistRules{Rules: []SmartRule{
+			rule("category", "not_in", `["sleep"]`),
+			rule("artist", "contains", `"nina"`),
+			rule("owner", "in", `[1, 2]`),
+			rule("favorited_within", "lt", `30`),
+			rule("uploaded_within", "lt", `7.5`),
+			rule("duration", "between", `[60, 300]`),
+		}}},
+		{name: "no rules", rules: SmartPlaylistRules{}, err: true},
+		{name: "bad match", rules: SmartPlaylistRules{Match: "some", Rules: []SmartRule{rule("genre", "in", `["a"]`)}}, err: true},
+		{name: "bad sort", rules: SmartPlaylistRules{Sort: "loudest", Rules: []SmartRule{rule("genre", "in", `["a"]`)}}, err: true},
+		{name: "limit too high", rules: SmartPlaylistRules{Limit: maxSmartLimit + 1, Rules: []SmartRule{rule("genre", "in", `["a"]`)}}, err: true},
+		{name: "negative limit", rules: SmartPlaylistRules{Limit: -1, Rules: []SmartRule{rule("genre", "in", `["a"]`)}}, err: true},
+		{name: "too many rules", rules: SmartPlaylistRules{Rules: tooMany}, err: true},
+		{name: "empty group", rules: SmartPlaylistRules{Groups: []SmartRuleGroup{{}}}, err: true},
+		{name: "bad group match", rules: SmartPlaylistRules{Groups: []SmartRuleGroup{{Match: "x", Rules: []SmartRule{rule("genre", "in", `["a"]`)}}}}, err: true},
+		{name: "invalid rule in group", rules: SmartPlaylistRules{Groups: []SmartRuleGroup{{Rules: []SmartRule{rule("genre", "contains", `"a"`)}}}}, err: true},
+		{name: "unknown field", rules: SmartPlaylistRules{Rules: []SmartRule{rule("bpm", "gt", `120`)}}, err: true},
+		{name: "unsupported operator", rules: SmartPlaylistRules{Rules: []SmartRule{rule("title", "in", `["a"]`)}}, err: true},
+		{name: "empty contains", rules: SmartPlaylistRules{Rules: []SmartRule{rule("title", "contains", `""`)}}, err: true},
+		{name: "empty list", rules: SmartPlaylistRules{Rules: []SmartRule{rule("genre", "in", `[]`)}}, err: true},
+		{name: "owner names", rules: SmartPlaylistRules{Rules: []SmartRule{rule("owner", "in", `["bob"]`)}}, err: true},
+		{name: "followed not bool", rules: SmartPlaylistRules{Rules: []SmartRule{rule("followed", "is", `"yes"`)}}, err: true},
+		{name: "reversed range", rules: SmartPlaylistRules{Rules: []SmartRule{rule("duration", "between", `[300, 60]`)}}, err: true},
+		{name: "short range", rules: SmartPlaylistRules{Rules: []SmartRule{rule("duration", "between", `[60]`)}}, err: true},
+		{name: "negative days", rules: SmartPlaylistRules{Rules: []SmartRule{rule("uploaded_within", "lt", `-1`)}}, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.Normalize()
+			if (err != nil) != tt.err {
+				t.Fatalf("Normalize() error = %v, want error %v", err, tt.err)
+			}
+			if tt.want == nil {
+				return
+			}
+			if tt.rules.Match != tt.want.Match || tt.rules.Sort != tt.want.Sort || tt.rules.Limit != tt.want.Limit {
+				t.Errorf("Normalize() = match %q sort %q limit %d, want match %q sort %q limit %d",
+					tt.rules.Match, tt.rules.Sort, tt.rules.Limit, tt.want.Match, tt.want.Sort, tt.want.Limit)
+			}
+			for i, group := range tt.rules.Groups {
+				if group.Match != "all" {
+					t.Errorf("Groups[%d].Match = %q, want \"all\"", i, group.Match)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// UploadSession tracks a resumable upload. The bytes received so far live in a
+// partial file on local disk until the session is finalized.
+type UploadSession struct {
+	ID          string    `gorm:"column:id;primaryKey"`
+	Owner       uint      `gorm:"column:owner_id;not null;index"`
+	Filename    string    `gorm:"column:filename"`
+	ContentType string    `gorm:"column:content_type"`
+	Size        int64     `gorm:"column:size;not null"`
+	Offset      int64     `gorm:"column:upload_offset;not null;default:0"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at"`
+	ExpiresAt   time.Time `gorm:"column:expires_at;index"`
+}
+
+func (s *UploadSession) IsComplete() bool {
+	return s.Offset == s.Size
+}
+
+func (s *UploadSession) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
+
+type CreateUploadSession struct {
+	Filename    string `json:"filename" validate:"required,max=255"`
+	Size        int64  `json:"size" validate:"required,min=1"`
+	ContentType string `json:"contentType"`
+}
@@ -18,7 +18,8 @@ type User struct {
 	AvatarPublicID string   `gorm:"column:avatar_public_id;validate:'omitempty,alphanum'"`
 	Verified       bool     `gorm:"column:verified"`
 	IsAdmin        bool     `gorm:"column:is_admin"`
-	Favorites      []*Audio `gorm:"many2many:user_favorites;"`
+	Banned         bool     `gorm:"column:banned;default:false"`
+	HistoryPaused  bool     `gorm:"column:history_paused;default:false"`
 	Tokens         []*Token `gorm:"foreignKey:UserID"`
 }
 
@@ -30,12 +31,24 @@ type User_Relations struct {
 	Following   User `gorm:"foreignKey:FollowingID"`
 }
 
+// Token types stored in Token.Type. Access tokens are short-lived JWTs sent as
+// bearer tokens; refresh tokens are opaque, stored hashed and rotated on use.
+// Both carry the Family of the sign-in session they belong to.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
 type Token struct {
 	gorm.Model
-	UserID    uint      `gorm:"index"`
-	Token     string    `gorm:"column:token;unique"`
-	Type      string    `gorm:"column:type"`
-	ExpiresAt time.Time `gorm:"column:expires_at"`
+	UserID     uint      `gorm:"index"`
+	Token      string    `gorm:"column:token;unique"`
+	Type       string    `gorm:"column:type"`
+	Family     string    `gorm:"column:family;index"`
+	Device     string    `gorm:"column:device"`
+	IP         string    `gorm:"column:ip"`
+	LastSeenAt time.Time `gorm:"column:last_seen_at"`
+	ExpiresAt  time.Time `gorm:"column:expires_at"`
 }
 
 // save user details
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	WaveformPending     = "pending"
+	WaveformReady       = "ready"
+	WaveformUnavailable = "unavailable"
+)
+
+// Waveform holds the peaks of an audio at one resolution. Peaks are stored as
+// interleaved min/max pairs of signed 8-bit samples, one pair per bucket.
+type Waveform struct {
+	AudioID    uint      `gorm:"column:audio_id;primaryKey"`
+	Resolution int       `gorm:"column:resolution;primaryKey"`
+	Peaks      []byte    `gorm:"column:peaks;not null"`
+	CreatedAt  time.Time `gorm:"column:created_at"`
+}
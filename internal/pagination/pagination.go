@@ -0,0 +1,129 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position of the last row of a page in (created_at, id) order.
+// Lists kept in a user-defined order, such as playlist tracks, also carry the
+// position of the row. Clients receive it as an opaque string.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"id"`
+	Position  int       `json:"p,omitempty"`
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(b, &cursor); err != nil || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// Page is a request for up to Limit rows following After, newest first.
+type Page struct {
+	Limit int
+	After *Cursor
+}
+
+/*
+* FromQuery reads the 'limit' and 'cursor' query parameters.
+* The limit defaults to DefaultLimit and is capped at MaxLimit.
+ */
+func FromQuery(c *gin.Context) (Page, error) {
+	page := Page{Limit: DefaultLimit}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err == nil && limit > 0 {
+			page.Limit = limit
+		}
+	}
+	if page.Limit > MaxLimit {
+		page.Limit = MaxLimit
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := DecodeCursor(raw)
+		if err != nil {
+			return page, err
+		}
+		page.After = cursor
+	}
+
+	return page, nil
+}
+
+/*
+* Scope orders a query newest first by the given columns, skips everything up to the cursor
+* and fetches one row more than the limit so Trim can tell whether another page exists.
+ */
+func (p Page) Scope(createdAtColumn, idColumn string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if p.After != nil {
+			db = db.Where(fmt.Sprintf("(%s, %s) < (?, ?)", createdAtColumn, idColumn), p.After.CreatedAt, p.After.ID)
+		}
+		return db.Order(fmt.Sprintf("%s DESC, %s DESC", createdAtColumn, idColumn)).Limit(p.Limit + 1)
+	}
+}
+
+/*
+* PositionScope orders a query by position, then by the given creation time and id columns, skips
+* everything up to the cursor and fetches one row more than the limit, like Scope
+ */
+func (p Page) PositionScope(positionColumn, createdAtColumn, idColumn string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if p.After != nil {
+			db = db.Where(fmt.Sprintf("(%s, %s, %s) > (?, ?, ?)", positionColumn, createdAtColumn, idColumn),
+				p.After.Position, p.After.CreatedAt, p.After.ID)
+		}
+		return db.Order(fmt.Sprintf("%s, %s, %s", positionColumn, createdAtColumn, idColumn)).Limit(p.Limit + 1)
+	}
+}
+
+/*
+* Trim drops the extra row fetched by Scope and returns the cursor of the next page, if any
+ */
+func Trim[T any](p Page, items []T, cursorOf func(T) Cursor) ([]T, *string) {
+	if len(items) <= p.Limit {
+		return items, nil
+	}
+
+	items = items[:p.Limit]
+	next := cursorOf(items[len(items)-1]).Encode()
+	return items, &next
+}
+
+// Envelope is the response body shared by every paginated list.
+func Envelope(items interface{}, nextCursor *string) gin.H {
+	return gin.H{
+		"items":       items,
+		"next_cursor": nextCursor,
+	}
+}
@@ -0,0 +1,133 @@
+package pagination
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC)
+
+	tests := []struct {
+		name   string
+		cursor Cursor
+	}{
+		{"time and id", Cursor{CreatedAt: createdAt, ID: 42}},
+		{"with position", Cursor{CreatedAt: createdAt, ID: 7, Position: 3}},
+		{"zero time", Cursor{ID: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := DecodeCursor(tt.cursor.Encode())
+			if err != nil {
+				t.Fatalf("DecodeCursor() error = %v", err)
+			}
+			if !decoded.CreatedAt.Equal(tt.cursor.CreatedAt) || decoded.ID != tt.cursor.ID || decoded.Position != tt.cursor.Position {
+				t.Errorf("DecodeCursor() = %+v, want %+v", *decoded, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"not base64", "!!!"},
+		{"not json", "bm90IGpzb24"},
+		{"missing id", Cursor{CreatedAt: time.Now()}.Encode()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.raw); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor(%q) error = %v, want %v", tt.raw, err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cursor := Cursor{CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ID: 9}
+
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		after *Cursor
+		err   error
+	}{
+		{"defaults", "", DefaultLimit, nil, nil},
+		{"custom limit", "limit=5", 5, nil, nil},
+		{"capped limit", "limit=1000", MaxLimit, nil, nil},
+		{"zero limit", "limit=0", DefaultLimit, nil, nil},
+		{"negative limit", "limit=-3", DefaultLimit, nil, nil},
+		{"malformed limit", "limit=ten", DefaultLimit, nil, nil},
+		{"cursor", "cursor=" + cursor.Encode(), DefaultLimit, &cursor, nil},
+		{"invalid cursor", "cursor=garbage!", DefaultLimit, nil, ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/?"+tt.query, nil)
+
+			page, err := FromQuery(c)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("FromQuery() error = %v, want %v", err, tt.err)
+			}
+			if page.Limit != tt.limit {
+				t.Errorf("Limit = %d, want %d", page.Limit, tt.limit)
+			}
+			if (page.After == nil) != (tt.after == nil) {
+				t.Fatalf("After = %+v, want %+v", page.After, tt.after)
+			}
+			if tt.after != nil && (page.After.ID != tt.after.ID || !page.After.CreatedAt.Equal(tt.after.CreatedAt)) {
+				t.Errorf("After = %+v, want %+v", *page.After, *tt.after)
+			}
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	cursorOf := func(id uint) Cursor { return Cursor{ID: id} }
+
+	tests := []struct {
+		name  string
+		limit int
+		items []uint
+		want  []uint
+		next  *Cursor
+	}{
+		{"empty", 3, nil, nil, nil},
+		{"fewer than limit", 3, []uint{5, 4}, []uint{5, 4}, nil},
+		{"exactly limit", 3, []uint{5, 4, 3}, []uint{5, 4, 3}, nil},
+		{"one extra row", 3, []uint{5, 4, 3, 2}, []uint{5, 4, 3}, &Cursor{ID: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, next := Trim(Page{Limit: tt.limit}, tt.items, cursorOf)
+			if !reflect.DeepEqual(items, tt.want) {
+				t.Errorf("Trim() items = %v, want %v", items, tt.want)
+			}
+			if tt.next == nil {
+				if next != nil {
+					t.Errorf("Trim() next = %q, want none", *next)
+				}
+				return
+			}
+			if next == nil || *next != tt.next.Encode() {
+				t.Errorf("Trim() next = %v, want %q", next, tt.next.Encode())
+			}
+		})
+	}
+}
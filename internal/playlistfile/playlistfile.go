@@ -0,0 +1,273 @@
+package playlistfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatM3U8 = "m3u8"
+	FormatXSPF = "xspf"
+	FormatJSON = "json"
+)
+
+var (
+	ErrUnknownFormat = errors.New("playlistfile: unknown playlist format")
+	ErrMalformed     = errors.New("playlistfile: malformed playlist")
+)
+
+// Entry is a single track of a playlist file. Zero values mean the
+// information was not present.
+type Entry struct {
+	Line       int    `json:"line,omitempty"` // line of the location in M3U8 files, 1-based track number otherwise
+	ID         uint   `json:"id,omitempty"`
+	Identifier string `json:"identifier,omitempty"` // URI naming the track independently of where it is played from
+	Location   string `json:"location,omitempty"`
+	Title      string `json:"title,omitempty"`
+	Artist     string `json:"artist,omitempty"`
+	Album      string `json:"album,omitempty"`
+	Duration   int    `json:"duration,omitempty"` // seconds
+}
+
+// Playlist is the content of a playlist file.
+type Playlist struct {
+	Title   string  `json:"title"`
+	Entries []Entry `json:"tracks"`
+}
+
+/*
+* ContentType returns the MIME type and file extension of a format
+ */
+func ContentType(format string) (string, string, error) {
+	switch format {
+	case FormatM3U8:
+		return "application/vnd.apple.mpegurl", ".m3u8", nil
+	case FormatXSPF:
+		return "application/xspf+xml", ".xspf", nil
+	case FormatJSON:
+		return "application/json", ".json", nil
+	}
+	return "", "", ErrUnknownFormat
+}
+
+/*
+* DetectFormat guesses the format from the file name, falling back to the content
+ */
+func DetectFormat(filename string, data []byte) (string, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".m3u8", ".m3u":
+		return FormatM3U8, nil
+	case ".xspf":
+		return FormatXSPF, nil
+	case ".json":
+		return FormatJSON, nil
+	}
+
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("#EXTM3U")):
+		return FormatM3U8, nil
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return FormatXSPF, nil
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return FormatJSON, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+/*
+* Write encodes the playlist in the given format
+ */
+func Write(w io.Writer, format string, playlist Playlist) error {
+	switch format {
+	case FormatM3U8:
+		return writeM3U8(w, playlist)
+	case FormatXSPF:
+		return writeXSPF(w, playlist)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(playlist)
+	}
+	return ErrUnknownFormat
+}
+
+/*
+* Parse decodes a playlist in the given format
+ */
+func Parse(r io.Reader, format string) (*Playlist, error) {
+	switch format {
+	case FormatM3U8:
+		return parseM3U8(r)
+	case FormatXSPF:
+		return parseXSPF(r)
+	case FormatJSON:
+		return parseJSON(r)
+	}
+	return nil, ErrUnknownFormat
+}
+
+func writeM3U8(w io.Writer, playlist Playlist) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "#EXTM3U")
+	if playlist.Title != "" {
+		fmt.Fprintf(b, "#PLAYLIST:%s\n", oneLine(playlist.Title))
+	}
+	for _, entry := range playlist.Entries {
+		duration := entry.Duration
+		if duration == 0 {
+			duration = -1
+		}
+		name := entry.Title
+		if entry.Artist != "" {
+			name = entry.Artist + " - " + entry.Title
+		}
+		fmt.Fprintf(b, "#EXTINF:%d,%s\n", duration, oneLine(name))
+		fmt.Fprintln(b, oneLine(entry.Location))
+	}
+	return b.Flush()
+}
+
+func parseM3U8(r io.Reader) (*Playlist, error) {
+	playlist := &Playlist{}
+	scanner := bufio.NewScanner(r)
+
+	var pending Entry
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+
+		switch {
+		case text == "":
+		case strings.HasPrefix(text, "#PLAYLIST:"):
+			playlist.Title = strings.TrimSpace(strings.TrimPrefix(text, "#PLAYLIST:"))
+		case strings.HasPrefix(text, "#EXTINF:"):
+			pending = parseExtInf(strings.TrimPrefix(text, "#EXTINF:"))
+		case strings.HasPrefix(text, "#"):
+		default:
+			pending.Location = text
+			pending.Line = line
+			playlist.Entries = append(playlist.Entries, pending)
+			pending = Entry{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return playlist, nil
+}
+
+// parseExtInf reads "<duration>[ attributes],[Artist - ]Title".
+func parseExtInf(value string) Entry {
+	var entry Entry
+
+	info, name, _ := strings.Cut(value, ",")
+	if fields := strings.Fields(info); len(fields) > 0 {
+		if seconds, err := strconv.ParseFloat(fields[0], 64); err == nil && seconds > 0 {
+			entry.Duration = int(seconds + 0.5)
+		}
+	}
+
+	name = strings.TrimSpace(name)
+	if artist, title, ok := strings.Cut(name, " - "); ok {
+		entry.Artist = strings.TrimSpace(artist)
+		entry.Title = strings.TrimSpace(title)
+	} else {
+		entry.Title = name
+	}
+	return entry
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+	Version string      `xml:"version,attr"`
+	Title   string      `xml:"title,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location   string `xml:"location,omitempty"`
+	Identifier string `xml:"identifier,omitempty"`
+	Title      string `xml:"title,omitempty"`
+	Creator    string `xml:"creator,omitempty"`
+	Album      string `xml:"album,omitempty"`
+	Duration   int    `xml:"duration,omitempty"` // milliseconds
+}
+
+func writeXSPF(w io.Writer, playlist Playlist) error {
+	doc := xspfPlaylist{Version: "1", Title: playlist.Title}
+	for _, entry := range playlist.Entries {
+		doc.Tracks = append(doc.Tracks, xspfTrack{
+			Location:   entry.Location,
+			Identifier: entry.Identifier,
+			Title:      entry.Title,
+			Creator:    entry.Artist,
+			Album:      entry.Album,
+			Duration:   entry.Duration * 1000,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func parseXSPF(r io.Reader) (*Playlist, error) {
+	var doc xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, ErrMalformed
+	}
+
+	playlist := &Playlist{Title: strings.TrimSpace(doc.Title)}
+	for i, track := range doc.Tracks {
+		entry := Entry{
+			Line:       i + 1,
+			Identifier: strings.TrimSpace(track.Identifier),
+			Location:   strings.TrimSpace(track.Location),
+			Title:      strings.TrimSpace(track.Title),
+			Artist:     strings.TrimSpace(track.Creator),
+			Album:      strings.TrimSpace(track.Album),
+			Duration:   (track.Duration + 500) / 1000,
+		}
+		// Older exports wrote the bare audio ID as the identifier.
+		if id, err := strconv.ParseUint(entry.Identifier, 10, 0); err == nil {
+			entry.ID, entry.Identifier = uint(id), ""
+		}
+		playlist.Entries = append(playlist.Entries, entry)
+	}
+	return playlist, nil
+}
+
+func parseJSON(r io.Reader) (*Playlist, error) {
+	var playlist Playlist
+	if err := json.NewDecoder(r).Decode(&playlist); err != nil {
+		return nil, ErrMalformed
+	}
+	for i := range playlist.Entries {
+		playlist.Entries[i].Line = i + 1
+	}
+	return &playlist, nil
+}
+
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
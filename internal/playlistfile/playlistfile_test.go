@@ -0,0 +1,180 @@
+package playlistfile
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	playlist := Playlist{
+		Title: "Late night",
+		Entries: []Entry{
+			{
+				Line:       1,
+				Identifier: "https://api.example.com/audio/12/stream",
+				Location:   "https://api.example.com/audio/12/stream",
+				Title:      "Nocturne",
+				Artist:     "Ana Ruiz",
+				Duration:   215,
+			},
+			{
+				Line:     2,
+				Location: "https://cdn.example.com/tracks/rain.mp3",
+				Title:    "Rain",
+			},
+		},
+	}
+
+	tests := []struct {
+		format string
+		want   Playlist
+	}{
+		{
+			format: FormatJSON,
+			want:   playlist,
+		},
+		{
+			format: FormatXSPF,
+			want:   playlist,
+		},
+		{
+			// M3U8 has no identifiers and numbers entries by the line of their location.
+			format: FormatM3U8,
+			want: Playlist{
+				Title: "Late night",
+				Entries: []Entry{
+					{Line: 4, Location: playlist.Entries[0].Location, Title: "Nocturne", Artist: "Ana Ruiz", Duration: 215},
+					{Line: 6, Location: playlist.Entries[1].Location, Title: "Rain"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tt.format, playlist); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			got, err := Parse(&buf, tt.format)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse(Write()) = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteXSPFIdentifier(t *testing.T) {
+	playlist := Playlist{Entries: []Entry{{ID: 12, Identifier: "https://api.example.com/audio/12/stream"}}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatXSPF, playlist); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "<identifier>https://api.example.com/audio/12/stream</identifier>") {
+		t.Errorf("identifier is not the stream URL:\n%s", buf.String())
+	}
+}
+
+func TestParseXSPFBareIdentifier(t *testing.T) {
+	data := `<playlist version="1" xmlns="http://xspf.org/ns/0/"><trackList>
+<track><identifier> 12 </identifier><title>Nocturne</title></track>
+<track><identifier>urn:isrc:USRC17607839</identifier></track>
+</trackList></playlist>`
+
+	got, err := Parse(strings.NewReader(data), FormatXSPF)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []Entry{
+		{Line: 1, ID: 12, Title: "Nocturne"},
+		{Line: 2, Identifier: "urn:isrc:USRC17607839"},
+	}
+	if !reflect.DeepEqual(got.Entries, want) {
+		t.Errorf("Parse() entries = %+v, want %+v", got.Entries, want)
+	}
+}
+
+func TestParseM3U8(t *testing.T) {
+	data := "\ufeff#EXTM3U\n" +
+		"#PLAYLIST:Mix\n" +
+		"#EXTINF:12.6 tvg-id=\"x\",Solo Title\n" +
+		"song.mp3\n" +
+		"\n" +
+		"#EXTVLCOPT:network-caching=1000\n" +
+		"bare.mp3\n"
+
+	got, err := Parse(strings.NewReader(data), FormatM3U8)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := &Playlist{
+		Title: "Mix",
+		Entries: []Entry{
+			{Line: 4, Location: "song.mp3", Title: "Solo Title", Duration: 13},
+			{Line: 7, Location: "bare.mp3"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		format string
+		data   string
+		err    error
+	}{
+		{FormatXSPF, "<playlist", ErrMalformed},
+		{FormatJSON, "{\"tracks\": [", ErrMalformed},
+		{"pls", "[playlist]", ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.data), tt.format); !errors.Is(err, tt.err) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		filename string
+		data     string
+		format   string
+		err      error
+	}{
+		{"mix.m3u8", "", FormatM3U8, nil},
+		{"MIX.M3U", "", FormatM3U8, nil},
+		{"mix.xspf", "", FormatXSPF, nil},
+		{"mix.json", "", FormatJSON, nil},
+		{"upload", "\ufeff#EXTM3U\n", FormatM3U8, nil},
+		{"upload", "  <?xml version=\"1.0\"?>", FormatXSPF, nil},
+		{"upload", "{\"tracks\": []}", FormatJSON, nil},
+		{"upload.txt", "just text", "", ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			format, err := DetectFormat(tt.filename, []byte(tt.data))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("DetectFormat() error = %v, want %v", err, tt.err)
+			}
+			if format != tt.format {
+				t.Errorf("DetectFormat() = %q, want %q", format, tt.format)
+			}
+		})
+	}
+}
@@ -10,11 +10,22 @@ import (
 func SetAudioRoutes(router *gin.RouterGroup) {
 	router.POST("/create", middleware.IsAuthenticated, middleware.FileParserMiddleware(), controllers.CreateAudio)
 	router.PATCH("/:audioId", middleware.IsAuthenticated, middleware.FileParserMiddleware(), controllers.UpdateAudio)
+
+	// Resumable uploads
+	router.POST("/upload-sessions", middleware.IsAuthenticated, controllers.CreateUploadSession)
+	router.GET("/upload-sessions/:uploadId", middleware.IsAuthenticated, controllers.GetUploadSession)
+	router.HEAD("/upload-sessions/:uploadId", middleware.IsAuthenticated, controllers.GetUploadSession)
+	router.PATCH("/upload-sessions/:uploadId", middleware.IsAuthenticated, controllers.UploadChunk)
+	router.DELETE("/upload-sessions/:uploadId", middleware.IsAuthenticated, controllers.CancelUpload)
+	router.POST("/upload-sessions/:uploadId/complete", middleware.IsAuthenticated, middleware.FileParserMiddleware(), controllers.CompleteUpload)
+
 	router.GET("/recommendation", middleware.IsAuthenticated, controllers.GetSuggestionsList)
-	router.GET("/category", controllers.FilterByMood)
-	router.GET("/uploads/user/:userId", controllers.GetUploadsById)
+	router.GET("/category", middleware.OptionalAuthentication, controllers.FilterByMood)
+	router.GET("/uploads/user/:userId", middleware.OptionalAuthentication, controllers.GetUploadsById)
+	router.GET("/:audioId/stream", middleware.OptionalAuthentication, controllers.StreamAudio)
+	router.GET("/:audioId/waveform", middleware.OptionalAuthentication, controllers.GetWaveform)
 
-	router.GET("/", controllers.GetLatestAudios)
+	router.GET("/", middleware.OptionalAuthentication, controllers.GetLatestAudios)
 	router.GET("/latest-uploads", middleware.IsAuthenticated, controllers.GetLatestUploads)
 	router.GET("/search", middleware.IsAuthenticated, controllers.GeneralSearch)
 }
@@ -11,4 +11,8 @@ func SetFavoriteRoutes(router *gin.RouterGroup) {
 	router.POST("/add", middleware.IsAuthenticated, controllers.AddToFavorite)
 	router.POST("/delete", middleware.IsAuthenticated, controllers.DeleteFromFavorite)
 	router.GET("/my-favorite", middleware.IsAuthenticated, controllers.GetAllFavorites)
+	router.POST("/status", middleware.IsAuthenticated, controllers.GetFavoriteStatuses)
+
+	router.PUT("/:audioId", middleware.IsAuthenticated, controllers.PutFavorite)
+	router.DELETE("/:audioId", middleware.IsAuthenticated, controllers.RemoveFavorite)
 }
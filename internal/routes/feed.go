@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"backend/internal/controllers"
+	"backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetFeedRoutes(router *gin.RouterGroup) {
+	router.GET("/", middleware.IsAuthenticated, controllers.GetFeed)
+}
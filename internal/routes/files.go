@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"backend/internal/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetFileRoutes(router *gin.RouterGroup) {
+	router.GET("/*key", controllers.ServeFile)
+}
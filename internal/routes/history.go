@@ -1,9 +1,17 @@
 package routes
 
 import (
+	"backend/internal/controllers"
+	"backend/internal/middleware"
+
 	"github.com/gin-gonic/gin"
 )
 
 func SetHistoryRoutes(router *gin.RouterGroup) {
+	router.POST("/", middleware.IsAuthenticated, controllers.RecordPlay)
+	router.GET("/", middleware.IsAuthenticated, controllers.GetHistory)
+	router.DELETE("/", middleware.IsAuthenticated, controllers.ClearHistory)
+	router.DELETE("/:historyId", middleware.IsAuthenticated, controllers.DeleteHistoryEntry)
 
+	router.PATCH("/settings", middleware.IsAuthenticated, controllers.UpdateHistorySettings)
 }
@@ -15,8 +15,36 @@ func SetPlaylistRoutes(router *gin.RouterGroup) {
 	router.POST("/remove", middleware.IsAuthenticated, controllers.RemoveFromPlaylist)
 	router.DELETE("/delete/:playlistId", middleware.IsAuthenticated, controllers.DeletePlaylist)
 
+	// Ordered tracks
+	router.POST("/:playlistId/tracks", middleware.IsAuthenticated, controllers.AddPlaylistTracks)
+	router.DELETE("/:playlistId/tracks", middleware.IsAuthenticated, controllers.RemovePlaylistTracks)
+	router.PATCH("/:playlistId/tracks/:audioId", middleware.IsAuthenticated, controllers.MovePlaylistTrack)
+
+	router.POST("/:playlistId/fork", middleware.IsAuthenticated, controllers.ForkPlaylist)
+	router.POST("/:playlistId/share-token", middleware.IsAuthenticated, controllers.RotatePlaylistShareToken)
+
+	// Covers
+	router.PUT("/:playlistId/cover", middleware.IsAuthenticated, middleware.FileParserMiddleware(), controllers.UploadPlaylistCover)
+	router.DELETE("/:playlistId/cover", middleware.IsAuthenticated, controllers.DeletePlaylistCover)
+
+	// Import and export
+	router.POST("/import", middleware.IsAuthenticated, controllers.ImportPlaylist)
+	router.GET("/:playlistId/export", middleware.OptionalAuthentication, controllers.ExportPlaylist)
+
+	// Smart playlists
+	router.GET("/:playlistId/rules", middleware.IsAuthenticated, controllers.GetSmartPlaylistRules)
+	router.PUT("/:playlistId/rules", middleware.IsAuthenticated, controllers.UpdateSmartPlaylistRules)
+
+	// Collaboration
+	router.GET("/invitations", middleware.IsAuthenticated, controllers.ListInvitations)
+	router.GET("/:playlistId/collaborators", middleware.IsAuthenticated, controllers.ListCollaborators)
+	router.POST("/:playlistId/collaborators", middleware.IsAuthenticated, controllers.InviteCollaborator)
+	router.POST("/:playlistId/collaborators/accept", middleware.IsAuthenticated, controllers.AcceptInvitation)
+	router.DELETE("/:playlistId/collaborators/:userId", middleware.IsAuthenticated, controllers.RemoveCollaborator)
+	router.GET("/:playlistId/activity", middleware.IsAuthenticated, controllers.GetPlaylistActivity)
+
 	router.GET("/public", middleware.IsAuthenticated, controllers.GetPublicPlaylists)
-	router.GET("/:playlistId", middleware.IsAuthenticated, controllers.GetAudiosByPlaylist)
-	router.GET("/detail/:playlistId", middleware.IsAuthenticated, controllers.GetPlaylistDetailsByID)
+	router.GET("/:playlistId", middleware.OptionalAuthentication, controllers.GetAudiosByPlaylist)
+	router.GET("/detail/:playlistId", middleware.OptionalAuthentication, controllers.GetPlaylistDetailsByID)
 
 }
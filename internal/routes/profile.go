@@ -8,7 +8,7 @@ import (
 )
 
 func SetProfileRoutes(router *gin.RouterGroup) {
-	router.GET("/user/:userId", controllers.GetPublicProfile)
+	router.GET("/user/:userId", middleware.OptionalAuthentication, controllers.GetPublicProfile)
 
 	router.GET("/my-songs", middleware.IsAuthenticated, controllers.GetPersonalUploads)
 	router.GET("/my-playlist", middleware.IsAuthenticated, controllers.GetPersonalPlaylist)
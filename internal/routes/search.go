@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"backend/internal/controllers"
+	"backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetSearchRoutes(router *gin.RouterGroup) {
+	router.GET("/", middleware.IsAuthenticated, controllers.GeneralSearch)
+	router.GET("/suggest", controllers.SearchSuggest)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"backend/internal/controllers"
+	"backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetShareRoutes(router *gin.RouterGroup) {
+	router.POST("/links", middleware.IsAuthenticated, controllers.CreateShareLink)
+	router.GET("/links", middleware.IsAuthenticated, controllers.ListShareLinks)
+	router.DELETE("/links/:linkId", middleware.IsAuthenticated, controllers.RevokeShareLink)
+
+	router.GET("/:token", middleware.OptionalAuthentication, controllers.OpenShareLink)
+}
@@ -14,13 +14,21 @@ func SetUserRoutes(router *gin.RouterGroup) {
 	router.POST("/sign-up", controllers.CreateUser)
 	router.POST("/logout", middleware.IsAuthenticated, controllers.Signout)
 	router.POST("/is-auth", middleware.IsAuthenticated, controllers.SendProfile)
+	router.POST("/refresh", controllers.RefreshToken)
+
+	// Session management routes
+	router.GET("/sessions", middleware.IsAuthenticated, controllers.ListSessions)
+	router.DELETE("/sessions/:sessionId", middleware.IsAuthenticated, controllers.RevokeSession)
+	router.DELETE("/sessions", middleware.IsAuthenticated, controllers.RevokeOtherSessions)
 
 	// Email Verification Routes
 	router.POST("/verify", controllers.VerifyEmail)
 	router.POST("/re-verify", controllers.ReVerifyEmail)
 
 	// Password management routes
-	router.PATCH("/update-password", controllers.UpdatePassword)
+	router.PATCH("/update-password", middleware.IsAuthenticated, controllers.UpdatePassword)
+	router.POST("/forgot-password", controllers.ForgotPassword)
+	router.POST("/reset-password", controllers.ResetPassword)
 
 	// profile management route
 	router.PATCH("/check", middleware.IsAuthenticated, middleware.FileParserMiddleware(), controllers.UpdateProfile)
@@ -0,0 +1,124 @@
+package serializers
+
+import (
+	"backend/internal/models"
+	"backend/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AudioOwner is the public part of the user who uploaded an audio.
+type AudioOwner struct {
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	Avatar string `json:"avatar"`
+}
+
+// Audio is the JSON shape of an audio in every listing.
+type Audio struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	About       string     `json:"about"`
+	Category    string     `json:"category"`
+	Artist      string     `json:"artist"`
+	Album       string     `json:"album"`
+	Genre       string     `json:"genre"`
+	Duration    uint       `json:"duration"`
+	Visibility  string     `json:"visibility"`
+	File        string     `json:"file"`
+	Poster      string     `json:"poster"`
+	Owner       AudioOwner `json:"owner"`
+	IsFavorited bool       `json:"is_favorited"`
+	LikeCount   int64      `json:"like_count"`
+	PlayCount   int64      `json:"play_count"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type audioStats struct {
+	AudioID     uint
+	PlayCount   int64
+	LikeCount   int64
+	IsFavorited bool
+}
+
+/*
+* Audios converts audios to their JSON shape, keeping their order.
+* Owners are loaded in one query; play counts, like counts and the viewer's favorites in another.
+* Pass a viewerID of 0 for anonymous callers.
+ */
+func Audios(db *gorm.DB, audios []models.Audio, viewerID uint) ([]Audio, error) {
+	result := make([]Audio, len(audios))
+	if len(audios) == 0 {
+		return result, nil
+	}
+
+	audioIDs := make([]uint, 0, len(audios))
+	ownerIDs := make([]uint, 0, len(audios))
+	seenOwners := make(map[uint]bool)
+	for _, audio := range audios {
+		audioIDs = append(audioIDs, audio.ID)
+		if audio.Owner == nil && !seenOwners[audio.OwnerID] {
+			seenOwners[audio.OwnerID] = true
+			ownerIDs = append(ownerIDs, audio.OwnerID)
+		}
+	}
+
+	owners := make(map[uint]AudioOwner)
+	if len(ownerIDs) > 0 {
+		var users []models.User
+		if err := db.Select("id", "name", "avatar_url").Where("id IN ?", ownerIDs).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			owners[user.ID] = AudioOwner{ID: user.ID, Name: user.Name, Avatar: utils.FileURL(user.AvatarURL)}
+		}
+	}
+
+	var stats []audioStats
+	if err := db.Table("audios").
+		Select(`audios.id AS audio_id,
+			(SELECT count(*) FROM histories WHERE histories.audio_id = audios.id AND histories.deleted_at IS NULL) AS play_count,
+			(SELECT count(*) FROM favorites WHERE favorites.target_type = 'audio' AND favorites.target_id = audios.id) AS like_count,
+			EXISTS (SELECT 1 FROM favorites WHERE favorites.target_type = 'audio' AND favorites.target_id = audios.id AND favorites.user_id = ?) AS is_favorited`, viewerID).
+		Where("audios.id IN ?", audioIDs).
+		Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+
+	statsByAudio := make(map[uint]audioStats, len(stats))
+	for _, s := range stats {
+		statsByAudio[s.AudioID] = s
+	}
+
+	for i, audio := range audios {
+		owner, ok := owners[audio.OwnerID]
+		if audio.Owner != nil {
+			owner, ok = AudioOwner{ID: audio.Owner.ID, Name: audio.Owner.Name, Avatar: utils.FileURL(audio.Owner.AvatarURL)}, true
+		}
+		if !ok {
+			owner = AudioOwner{ID: audio.OwnerID}
+		}
+
+		result[i] = Audio{
+			ID:          audio.ID,
+			Title:       audio.Title,
+			About:       audio.About,
+			Category:    audio.Category,
+			Artist:      audio.Artist,
+			Album:       audio.Album,
+			Genre:       audio.Genre,
+			Duration:    audio.Duration,
+			Visibility:  audio.Visibility,
+			File:        utils.FileURL(audio.AudioURL),
+			Poster:      utils.FileURL(audio.CoverURL),
+			Owner:       owner,
+			IsFavorited: statsByAudio[audio.ID].IsFavorited,
+			LikeCount:   statsByAudio[audio.ID].LikeCount,
+			PlayCount:   statsByAudio[audio.ID].PlayCount,
+			CreatedAt:   audio.CreatedAt,
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/admin"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+)
+
+// Cloudinary stores assets by public ID without a resource type, so lookups
+// try each type in turn.
+var cloudinaryAssetTypes = []api.AssetType{api.Image, api.Video, api.File}
+
+// cloudinaryStatTTL is how long asset metadata is reused before the Admin API is asked again.
+const cloudinaryStatTTL = 10 * time.Minute
+
+type cachedStat struct {
+	info    ObjectInfo
+	expires time.Time
+}
+
+// CloudinaryStorage keeps files in Cloudinary. Keys are Cloudinary public IDs.
+// Asset metadata is cached because every lookup costs up to one Admin API call
+// per asset type, and the Admin API is rate limited.
+type CloudinaryStorage struct {
+	cld    *cloudinary.Cloudinary
+	client *http.Client
+
+	mu    sync.Mutex
+	stats map[string]cachedStat
+}
+
+func NewCloudinaryStorage(cld *cloudinary.Cloudinary) *CloudinaryStorage {
+	return &CloudinaryStorage{cld: cld, client: http.DefaultClient, stats: make(map[string]cachedStat)}
+}
+
+func (s *CloudinaryStorage) cachedStat(key string) (*ObjectInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached, ok := s.stats[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(cached.expires) {
+		delete(s.stats, key)
+		return nil, false
+	}
+	info := cached.info
+	return &info, true
+}
+
+func (s *CloudinaryStorage) cacheStat(key string, info *ObjectInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for cachedKey, cached := range s.stats {
+		if now.After(cached.expires) {
+			delete(s.stats, cachedKey)
+		}
+	}
+	if info == nil {
+		delete(s.stats, key)
+		return
+	}
+	s.stats[key] = cachedStat{info: *info, expires: now.Add(cloudinaryStatTTL)}
+}
+
+/*
+* Put uploads the file and returns its public ID as the key. Cloudinary keeps the
+* format separately, so the extension of the requested key is dropped.
+ */
+func (s *CloudinaryStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*ObjectInfo, error) {
+	result, err := s.cld.Upload.Upload(ctx, r, uploader.UploadParams{
+		PublicID:     strings.TrimSuffix(key, path.Ext(key)),
+		ResourceType: string(api.Auto),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Error.Message != "" {
+		return nil, fmt.Errorf("storage: cloudinary upload failed: %s", result.Error.Message)
+	}
+
+	info := &ObjectInfo{
+		Key:         result.PublicID,
+		URL:         result.SecureURL,
+		Size:        int64(result.Bytes),
+		ModTime:     result.CreatedAt,
+		ContentType: contentTypeFor(result.PublicID+"."+result.Format, contentType),
+		ETag:        `"` + result.Etag + `"`,
+	}
+	s.cacheStat(info.Key, info)
+	return info, nil
+}
+
+func (s *CloudinaryStorage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	if info, ok := s.cachedStat(key); ok {
+		return info, nil
+	}
+
+	for _, assetType := range cloudinaryAssetTypes {
+		result, err := s.cld.Admin.Asset(ctx, admin.AssetParams{
+			AssetType:    assetType,
+			DeliveryType: "upload",
+			PublicID:     key,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if result.Error.Message != "" {
+			continue
+		}
+
+		info := &ObjectInfo{
+			Key:         result.PublicID,
+			URL:         result.SecureURL,
+			Size:        int64(result.Bytes),
+			ModTime:     result.CreatedAt,
+			ContentType: contentTypeFor(result.PublicID+"."+result.Format, ""),
+			ETag:        `"` + result.Etag + `"`,
+		}
+		s.cacheStat(key, info)
+		return info, nil
+	}
+
+	return nil, ErrNotFound
+}
+
+/*
+* Open returns a reader that fetches the asset from the CDN with Range requests,
+* so seeking does not download the whole file.
+ */
+func (s *CloudinaryStorage) Open(ctx context.Context, key string) (*Object, error) {
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &httpRangeReader{ctx: ctx, client: s.client, url: info.URL, size: info.Size}
+	return &Object{ReadSeekCloser: reader, ObjectInfo: *info}, nil
+}
+
+func (s *CloudinaryStorage) Delete(ctx context.Context, key string) error {
+	s.cacheStat(key, nil)
+	for _, assetType := range cloudinaryAssetTypes {
+		result, err := s.cld.Upload.Destroy(ctx, uploader.DestroyParams{
+			PublicID:     key,
+			ResourceType: string(assetType),
+		})
+		if err != nil {
+			return err
+		}
+		if result.Result == "ok" {
+			return nil
+		}
+	}
+	return nil
+}
+
+/*
+* SignedURL returns the delivery URL of the asset. Assets are uploaded with the public
+* "upload" delivery type, so the URL does not expire and ttl is ignored.
+ */
+func (s *CloudinaryStorage) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return info.URL, nil
+}
+
+// httpRangeReader is an io.ReadSeekCloser over a remote URL that supports Range requests.
+type httpRangeReader struct {
+	ctx    context.Context
+	client *http.Client
+	url    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (r *httpRangeReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.body == nil {
+		req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.url, nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", r.offset))
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+
+		if resp.StatusCode != http.StatusPartialContent && !(resp.StatusCode == http.StatusOK && r.offset == 0) {
+			resp.Body.Close()
+			return 0, fmt.Errorf("storage: unexpected status %d fetching %s", resp.StatusCode, r.url)
+		}
+		r.body = resp.Body
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *httpRangeReader) Seek(offset int64, whence int) (int64, error) {
+	var next int64
+	switch whence {
+	case io.SeekStart:
+		next = offset
+	case io.SeekCurrent:
+		next = r.offset + offset
+	case io.SeekEnd:
+		next = r.size + offset
+	default:
+		return 0, fmt.Errorf("storage: invalid whence %d", whence)
+	}
+
+	if next < 0 {
+		return 0, fmt.Errorf("storage: negative position")
+	}
+
+	if next != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = next
+	return next, nil
+}
+
+func (r *httpRangeReader) Close() error {
+	if r.body != nil {
+		return r.body.Close()
+	}
+	return nil
+}
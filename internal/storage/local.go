@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignature is returned when a signed local URL is tampered with or expired.
+var ErrInvalidSignature = errors.New("storage: invalid or expired signature")
+
+// LocalStorage keeps files under a root directory on the local disk and
+// serves them through the /files route mounted at BaseURL. Stored URLs are
+// plain links that only name the key; clients get links signed with an
+// expiry from SignedURL when responses are built.
+type LocalStorage struct {
+	Root    string
+	BaseURL string
+	Secret  []byte
+}
+
+func NewLocalStorage(root, baseURL, secret string) *LocalStorage {
+	return &LocalStorage{
+		Root:    root,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Secret:  []byte(secret),
+	}
+}
+
+/*
+* path resolves a key inside the root directory. Keys that are not clean paths, such as ones with
+* ".." segments, are rejected rather than rewritten so two spellings never name the same file.
+ */
+func (s *LocalStorage) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" || cleaned != "/"+strings.TrimPrefix(key, "/") || strings.Contains(key, "\x00") {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(cleaned)), nil
+}
+
+func (s *LocalStorage) link(key string) string {
+	return s.BaseURL + "/files/" + strings.TrimPrefix(key, "/")
+}
+
+/*
+* KeyOf returns the key a stored link of this backend points to. Any query string is ignored,
+* so links stored with a signature by earlier versions resolve as well.
+ */
+func (s *LocalStorage) KeyOf(link string) (string, bool) {
+	link, _, _ = strings.Cut(link, "?")
+	key, ok := strings.CutPrefix(link, s.BaseURL+"/files/")
+	if !ok || key == "" {
+		return "", false
+	}
+	return key, true
+}
+
+func (s *LocalStorage) info(key string, fi os.FileInfo) *ObjectInfo {
+	return &ObjectInfo{
+		Key:         key,
+		URL:         s.link(key),
+		Size:        fi.Size(),
+		ModTime:     fi.ModTime(),
+		ContentType: mime.TypeByExtension(filepath.Ext(key)),
+		ETag:        fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()),
+	}
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*ObjectInfo, error) {
+	dest, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return nil, err
+	}
+
+	// Write to a temporary file first so readers never see a partial object.
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return nil, err
+	}
+
+	return s.Stat(ctx, key)
+}
+
+func (s *LocalStorage) Open(ctx context.Context, key string) (*Object, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if fi.IsDir() {
+		file.Close()
+		return nil, ErrNotFound
+	}
+
+	return &Object{ReadSeekCloser: file, ObjectInfo: *s.info(key, fi)}, nil
+}
+
+func (s *LocalStorage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if fi.IsDir() {
+		return nil, ErrNotFound
+	}
+
+	return s.info(key, fi), nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if _, err := s.Stat(ctx, key); err != nil {
+		return "", err
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.sign(key, expires))
+
+	return s.link(key) + "?" + query.Encode(), nil
+}
+
+/*
+* VerifySignature checks the expires/signature pair produced by SignedURL
+ */
+func (s *LocalStorage) VerifySignature(key, expires, signature string) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.sign(key, expires))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *LocalStorage) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
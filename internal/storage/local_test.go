@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStorage(t *testing.T) *LocalStorage {
+	return NewLocalStorage(t.TempDir(), "https://api.example.com/", "test-secret")
+}
+
+func TestLocalStorageObjects(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	tests := []struct {
+		name        string
+		key         string
+		data        string
+		contentType string
+	}{
+		{"audio", "audio/track.mp3", "ID3 audio bytes", "audio/mpeg"},
+		{"nested folders", "covers/2024/01/art.png", "png bytes", "image/png"},
+		{"leading slash", "/avatars/me.jpg", "jpeg bytes", "image/jpeg"},
+		{"empty file", "audio/empty.wav", "", "audio/x-wav"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := s.Put(ctx, tt.key, strings.NewReader(tt.data), int64(len(tt.data)), tt.contentType)
+			if err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if info.Size != int64(len(tt.data)) {
+				t.Errorf("Put() size = %d, want %d", info.Size, len(tt.data))
+			}
+			if want := "https://api.example.com/files/" + strings.TrimPrefix(tt.key, "/"); info.URL != want {
+				t.Errorf("Put() URL = %q, want %q", info.URL, want)
+			}
+
+			stat, err := s.Stat(ctx, tt.key)
+			if err != nil {
+				t.Fatalf("Stat() error = %v", err)
+			}
+			if stat.Size != info.Size || stat.ETag != info.ETag {
+				t.Errorf("Stat() = %+v, want size %d and etag %s", stat, info.Size, info.ETag)
+			}
+
+			object, err := s.Open(ctx, tt.key)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			got, err := io.ReadAll(object)
+			object.Close()
+			if err != nil || string(got) != tt.data {
+				t.Errorf("Open() content = %q, %v, want %q", got, err, tt.data)
+			}
+
+			if err := s.Delete(ctx, tt.key); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if _, err := s.Stat(ctx, tt.key); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Stat() after Delete() error = %v, want %v", err, ErrNotFound)
+			}
+			if _, err := s.Open(ctx, tt.key); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Open() after Delete() error = %v, want %v", err, ErrNotFound)
+			}
+			if err := s.Delete(ctx, tt.key); err != nil {
+				t.Errorf("second Delete() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestLocalStorageRejectsInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	outside := filepath.Join(filepath.Dir(s.Root), "outside.txt")
+	defer os.Remove(outside)
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"parent directory", "../outside.txt"},
+		{"parent inside path", "audio/../../outside.txt"},
+		{"dot segment", "audio/./track.mp3"},
+		{"double slash", "audio//track.mp3"},
+		{"root", "/"},
+		{"empty", ""},
+		{"nul byte", "audio/track\x00.mp3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Put(ctx, tt.key, strings.NewReader("data"), 4, ""); err == nil {
+				t.Errorf("Put(%q) succeeded, want error", tt.key)
+			}
+			if _, err := s.Open(ctx, tt.key); err == nil {
+				t.Errorf("Open(%q) succeeded, want error", tt.key)
+			}
+			if _, err := s.Stat(ctx, tt.key); err == nil {
+				t.Errorf("Stat(%q) succeeded, want error", tt.key)
+			}
+			if err := s.Delete(ctx, tt.key); err == nil {
+				t.Errorf("Delete(%q) succeeded, want error", tt.key)
+			}
+		})
+	}
+
+	if _, err := os.Stat(outside); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("a file was written outside the root: %v", err)
+	}
+}
+
+func TestLocalStorageRangeRead(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	data := "0123456789abcdefghij"
+	if _, err := s.Put(ctx, "audio/range.bin", strings.NewReader(data), int64(len(data)), ""); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		offset int64
+		whence int
+		length int
+		want   string
+	}{
+		{"from start", 0, io.SeekStart, 4, "0123"},
+		{"middle", 10, io.SeekStart, 5, "abcde"},
+		{"suffix", -3, io.SeekEnd, 3, "hij"},
+		{"past the end", 18, io.SeekStart, 5, "ij"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := s.Open(ctx, "audio/range.bin")
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer object.Close()
+
+			if _, err := object.Seek(tt.offset, tt.whence); err != nil {
+				t.Fatalf("Seek() error = %v", err)
+			}
+			got, err := io.ReadAll(io.LimitReader(object, int64(tt.length)))
+			if err != nil || string(got) != tt.want {
+				t.Errorf("read = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalStorageSignatures(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	key := "covers/art.png"
+	if _, err := s.Put(ctx, key, strings.NewReader("png"), 3, ""); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	signed, err := s.SignedURL(ctx, key, time.Hour)
+	if err != nil {
+		t.Fatalf("SignedURL() error = %v", err)
+	}
+	parsed, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("SignedURL() = %q is not a URL: %v", signed, err)
+	}
+	if parsed.Path != "/files/"+key {
+		t.Errorf("SignedURL() path = %q, want %q", parsed.Path, "/files/"+key)
+	}
+	expires, signature := parsed.Query().Get("expires"), parsed.Query().Get("signature")
+
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	tampered := []byte(signature)
+	tampered[0] ^= 1
+
+	tests := []struct {
+		name      string
+		key       string
+		expires   string
+		signature string
+		err       error
+	}{
+		{"valid", key, expires, signature, nil},
+		{"tampered signature", key, expires, string(tampered), ErrInvalidSignature},
+		{"other key", "covers/other.png", expires, signature, ErrInvalidSignature},
+		{"extended expiry", key, expires + "0", signature, ErrInvalidSignature},
+		{"expired", key, past, s.sign(key, past), ErrInvalidSignature},
+		{"missing expiry", key, "", s.sign(key, ""), ErrInvalidSignature},
+		{"missing signature", key, expires, "", ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.VerifySignature(tt.key, tt.expires, tt.signature); !errors.Is(err, tt.err) {
+				t.Errorf("VerifySignature() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	if _, err := s.SignedURL(ctx, "covers/missing.png", time.Hour); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SignedURL() of a missing file error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestLocalStorageKeyOf(t *testing.T) {
+	s := newTestStorage(t)
+
+	tests := []struct {
+		link string
+		key  string
+		ok   bool
+	}{
+		{"https://api.example.com/files/audio/track.mp3", "audio/track.mp3", true},
+		{"https://api.example.com/files/audio/track.mp3?signature=abc", "audio/track.mp3", true},
+		{"https://api.example.com/files/", "", false},
+		{"https://res.cloudinary.com/demo/track.mp3", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		key, ok := s.KeyOf(tt.link)
+		if key != tt.key || ok != tt.ok {
+			t.Errorf("KeyOf(%q) = %q, %v, want %q, %v", tt.link, key, ok, tt.key, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Storage keeps files in an S3 compatible bucket such as AWS S3 or MinIO.
+type S3Storage struct {
+	client    *minio.Client
+	bucket    string
+	publicURL string
+}
+
+/*
+* NewS3Storage connects to the endpoint. publicURL is the base the bucket is served from;
+* when empty, object URLs are built from the endpoint and bucket name.
+ */
+func NewS3Storage(endpoint, accessKey, secretKey, bucket, region string, useSSL bool, publicURL string) (*S3Storage, error) {
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+		Region: region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if publicURL == "" {
+		scheme := "http"
+		if useSSL {
+			scheme = "https"
+		}
+		publicURL = scheme + "://" + endpoint + "/" + bucket
+	}
+
+	return &S3Storage{client: client, bucket: bucket, publicURL: strings.TrimSuffix(publicURL, "/")}, nil
+}
+
+func (s *S3Storage) url(key string) string {
+	return s.publicURL + "/" + (&url.URL{Path: key}).EscapedPath()
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*ObjectInfo, error) {
+	if size <= 0 {
+		size = -1
+	}
+
+	result, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType: contentTypeFor(key, contentType),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ObjectInfo{
+		Key:         key,
+		URL:         s.url(key),
+		Size:        result.Size,
+		ModTime:     result.LastModified,
+		ContentType: contentTypeFor(key, contentType),
+		ETag:        `"` + result.ETag + `"`,
+	}, nil
+}
+
+func (s *S3Storage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	stat, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &ObjectInfo{
+		Key:         key,
+		URL:         s.url(key),
+		Size:        stat.Size,
+		ModTime:     stat.LastModified,
+		ContentType: stat.ContentType,
+		ETag:        `"` + stat.ETag + `"`,
+	}, nil
+}
+
+func (s *S3Storage) Open(ctx context.Context, key string) (*Object, error) {
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Object{ReadSeekCloser: object, ObjectInfo: *info}, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *S3Storage) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	signed, err := s.client.PresignedGetObject(ctx, s.bucket, key, ttl, url.Values{})
+	if err != nil {
+		return "", err
+	}
+	return signed.String(), nil
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when the requested key does not exist in the backend.
+var ErrNotFound = errors.New("storage: object not found")
+
+// ObjectInfo describes a stored file.
+type ObjectInfo struct {
+	Key         string
+	URL         string
+	Size        int64
+	ModTime     time.Time
+	ContentType string
+	ETag        string
+}
+
+// Object is an open, seekable handle on a stored file together with the
+// metadata needed to serve it over HTTP.
+type Object struct {
+	io.ReadSeekCloser
+	ObjectInfo
+}
+
+// Storage is a file backend. Keys are slash separated paths such as
+// "audio/<uuid>.mp3"; the URL returned by Put is safe to store and hand to clients.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*ObjectInfo, error)
+	Open(ctx context.Context, key string) (*Object, error)
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+/*
+* NewKey builds a unique key in the given folder, keeping the extension of the original file name
+ */
+func NewKey(folder, filename string) string {
+	return path.Join(folder, uuid.New().String()+path.Ext(filename))
+}
+
+func contentTypeFor(key, contentType string) string {
+	if contentType != "" {
+		return contentType
+	}
+	if byExt := mime.TypeByExtension(path.Ext(key)); byExt != "" {
+		return byExt
+	}
+	return "application/octet-stream"
+}
@@ -46,17 +46,53 @@ func SendVerificationMail(token string, profile Profile) error {
 
 	htmlContent := templates.GenerateTemplate(options)
 	m.SetBody("text/html", htmlContent)
+	attachTemplateImages(m)
 
-	basePath := "../internal/utils/templates"
+	if err := d.DialAndSend(m); err != nil {
+		fmt.Printf("Failed to send email: %v\n", err)
+		return err
+	}
 
-	m.Attach(filepath.Join(basePath, "logo.png"), gomail.SetHeader(map[string][]string{"Content-ID": {"<logo>"}}))
-	m.Attach(filepath.Join(basePath, "welcome.png"), gomail.SetHeader(map[string][]string{"Content-ID": {"<welcome>"}}))
+	fmt.Println("Email sent to:", profile.Email)
+	return nil
+}
+
+/*
+* This method sends a password reset link to user's email
+ */
+func SendPasswordResetMail(link string, profile Profile) error {
+	d := generateMailDialer()
+	m := gomail.NewMessage()
+
+	m.SetHeader("From", "email@audify.life")
+	m.SetHeader("To", profile.Email)
+	m.SetHeader("Subject", "Reset your Audify password")
+
+	options := templates.Options{
+		Title:     "Reset your password",
+		Message:   fmt.Sprintf("Hi %s, we received a request to reset your password. The link below is valid for one hour and can only be used once. If you did not ask for this, you can ignore this email.", profile.Name),
+		LogoCID:   "logo",
+		BannerCID: "welcome",
+		Link:      link,
+		BtnTitle:  "Reset password",
+	}
+
+	htmlContent := templates.GenerateTemplate(options)
+	m.SetBody("text/html", htmlContent)
+	attachTemplateImages(m)
 
 	if err := d.DialAndSend(m); err != nil {
 		fmt.Printf("Failed to send email: %v\n", err)
 		return err
 	}
 
-	fmt.Println("Email sent to:", profile.Email)
+	fmt.Println("Password reset email sent to:", profile.Email)
 	return nil
 }
+
+func attachTemplateImages(m *gomail.Message) {
+	basePath := "../internal/utils/templates"
+
+	m.Attach(filepath.Join(basePath, "logo.png"), gomail.SetHeader(map[string][]string{"Content-ID": {"<logo>"}}))
+	m.Attach(filepath.Join(basePath, "welcome.png"), gomail.SetHeader(map[string][]string{"Content-ID": {"<welcome>"}}))
+}
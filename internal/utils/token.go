@@ -1,23 +1,28 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"log"
-	"math/rand"
+	"math/big"
 	"strconv"
-	"time"
+	"strings"
 )
 
 /*
-* Random OTP Generator
+* Random OTP Generator backed by crypto/rand
  */
-func GenerateToken(length int) string {
-	rand.Seed(time.Now().UnixNano())
-	var otp string
+func GenerateToken(length int) (string, error) {
+	var otp strings.Builder
 	for i := 0; i < length; i++ {
-		otp += strconv.Itoa(rand.Intn(10))
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		otp.WriteString(strconv.Itoa(int(digit.Int64())))
 	}
-	return otp
+	return otp.String(), nil
 }
 
 func GenerateRandomHexString(byteLength int) string {
@@ -31,3 +36,11 @@ func GenerateRandomHexString(byteLength int) string {
 
 	return hex.EncodeToString(randomBytes)
 }
+
+/*
+* HashToken returns the hex encoded SHA-256 digest of an opaque token so it can be stored and looked up
+ */
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,54 +2,62 @@ package utils
 
 import (
 	"backend/internal/initializers"
+	"backend/internal/storage"
 	"context"
+	"io"
 	"mime/multipart"
-
-	"github.com/google/uuid"
-
-	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"time"
 )
 
 /*
-* This method helps in file upload in cloudinary
+* This method uploads a multipart file to the configured storage backend under the given folder
  */
-func UploadToCloudinary(file multipart.File, filePath string) (string, string, error) {
-	ctx := context.Background()
-	cld, err := initializers.SetupCloudinary()
+func UploadFile(ctx context.Context, fileHeader *multipart.FileHeader, folder string) (*storage.ObjectInfo, error) {
+	file, err := fileHeader.Open()
 	if err != nil {
-		return "", "", err
-	}
-
-	fileBaseName := uuid.New().String()
-
-	uploadParams := uploader.UploadParams{
-		PublicID: fileBaseName,
+		return nil, err
 	}
+	defer file.Close()
 
-	result, err := cld.Upload.Upload(ctx, file, uploadParams)
-	if err != nil {
-		return "", "", err
-	}
+	key := storage.NewKey(folder, fileHeader.Filename)
+	return initializers.Storage.Put(ctx, key, file, fileHeader.Size, fileHeader.Header.Get("Content-Type"))
+}
 
-	imageUrl := result.SecureURL
-	return imageUrl, result.PublicID, nil
+/*
+* This method uploads raw content, such as extracted cover art, to the configured storage backend
+ */
+func UploadReader(ctx context.Context, r io.Reader, size int64, folder, filename, contentType string) (*storage.ObjectInfo, error) {
+	key := storage.NewKey(folder, filename)
+	return initializers.Storage.Put(ctx, key, r, size, contentType)
 }
 
 /*
-* This method removes the image stored in the cloud
+* This method removes a file from the configured storage backend
  */
-func DestroyImage(publicId string) error {
-	ctx := context.Background()
-	cld, err := initializers.SetupCloudinary()
+func DeleteFile(ctx context.Context, key string) error {
+	return initializers.Storage.Delete(ctx, key)
+}
 
-	if err != nil {
-		return err
-	}
+// fileURLTTL is how long the file links handed out in responses stay valid.
+const fileURLTTL = 6 * time.Hour
 
-	destroyParams := uploader.DestroyParams{
-		PublicID: publicId,
+/*
+* This method turns a stored file URL into one clients can fetch. Local storage keeps unsigned links,
+* which are signed here with an expiry; URLs of other backends are returned unchanged.
+ */
+func FileURL(stored string) string {
+	local, ok := initializers.Storage.(*storage.LocalStorage)
+	if !ok {
+		return stored
+	}
+	key, ok := local.KeyOf(stored)
+	if !ok {
+		return stored
 	}
 
-	_, err = cld.Upload.Destroy(ctx, destroyParams)
-	return err
+	signed, err := local.SignedURL(context.Background(), key, fileURLTTL)
+	if err != nil {
+		return stored
+	}
+	return signed
 }
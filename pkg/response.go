@@ -13,3 +13,23 @@ func NewErrorResponse(code int, message string, data interface{}) ErrorResponse
 		Data:    data,
 	}
 }
+
+// HTTPError is an error that carries the status code and payload it should be reported with.
+// Handlers pass it to c.Error and the error handling middleware renders it as an ErrorResponse.
+type HTTPError struct {
+	Code    int
+	Message string
+	Data    interface{}
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+func NewHTTPError(code int, message string, data interface{}) *HTTPError {
+	return &HTTPError{
+		Code:    code,
+		Message: message,
+		Data:    data,
+	}
+}